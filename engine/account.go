@@ -0,0 +1,344 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sync"
+)
+
+// Balance represents a user's holding of a single asset
+type Balance struct {
+	Available int64 `json:"available"`
+	Reserved  int64 `json:"reserved"`
+}
+
+// Total returns available plus reserved funds
+func (b Balance) Total() int64 {
+	return b.Available + b.Reserved
+}
+
+// Settlement describes the balance movements of a single fill.
+// The buyer pays QuoteAmount out of reserved quote funds and receives BaseAmount,
+// the seller delivers BaseAmount out of reserved base funds and receives QuoteAmount.
+// A positive fee in the asset a party delivers is paid from its reservation,
+// any other fee (or rebate, when negative) is applied to available funds.
+type Settlement struct {
+	Buyer       string
+	Seller      string
+	BaseAsset   string
+	QuoteAsset  string
+	BaseAmount  int64
+	QuoteAmount int64
+
+	BuyerFee       int64
+	BuyerFeeAsset  string
+	SellerFee      int64
+	SellerFeeAsset string
+	FeeAccount     string
+}
+
+// AccountManager defines the interface for user balances and pre-trade reservations
+type AccountManager interface {
+	// Deposit credits available funds
+	Deposit(userID, asset string, amount int64) error
+	// Withdraw debits available funds
+	Withdraw(userID, asset string, amount int64) error
+	// Balance returns the current balance of an asset
+	Balance(userID, asset string) Balance
+	// Reserve moves funds from available to reserved
+	Reserve(userID, asset string, amount int64) error
+	// Release moves funds from reserved back to available
+	Release(userID, asset string, amount int64)
+	// Settle applies all legs of a fill atomically.
+	// The caller guarantees that both parties have reserved the amounts they deliver.
+	Settle(s Settlement)
+}
+
+// defaultInMemoryAccountManager provides a thread-safe in-memory implementation
+type defaultInMemoryAccountManager struct {
+	mu       sync.RWMutex
+	balances map[string]map[string]*Balance // UserID -> Asset -> Balance
+}
+
+// NewDefaultInMemoryAccountManager provides a thread-safe in-memory implementation
+func NewDefaultInMemoryAccountManager() AccountManager {
+	return &defaultInMemoryAccountManager{
+		balances: make(map[string]map[string]*Balance),
+	}
+}
+
+// balance returns the mutable balance entry, creating it if needed. Caller must hold the lock.
+func (m *defaultInMemoryAccountManager) balance(userID, asset string) *Balance {
+	assets, ok := m.balances[userID]
+	if !ok {
+		assets = make(map[string]*Balance)
+		m.balances[userID] = assets
+	}
+	b, ok := assets[asset]
+	if !ok {
+		b = &Balance{}
+		assets[asset] = b
+	}
+	return b
+}
+
+func (m *defaultInMemoryAccountManager) Deposit(userID, asset string, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.balance(userID, asset).Available += amount
+	return nil
+}
+
+func (m *defaultInMemoryAccountManager) Withdraw(userID, asset string, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.balance(userID, asset)
+	if b.Available < amount {
+		return ErrInsufficientFunds
+	}
+	b.Available -= amount
+	return nil
+}
+
+func (m *defaultInMemoryAccountManager) Balance(userID, asset string) Balance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if b, ok := m.balances[userID][asset]; ok {
+		return *b
+	}
+	return Balance{}
+}
+
+func (m *defaultInMemoryAccountManager) Reserve(userID, asset string, amount int64) error {
+	if amount < 0 {
+		return ErrInvalidAmount
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.balance(userID, asset)
+	if b.Available < amount {
+		return ErrInsufficientFunds
+	}
+	b.Available -= amount
+	b.Reserved += amount
+	return nil
+}
+
+func (m *defaultInMemoryAccountManager) Release(userID, asset string, amount int64) {
+	if amount <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.balance(userID, asset)
+	if amount > b.Reserved {
+		amount = b.Reserved
+	}
+	b.Reserved -= amount
+	b.Available += amount
+}
+
+func (m *defaultInMemoryAccountManager) Settle(s Settlement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.balance(s.Buyer, s.QuoteAsset).Reserved -= s.QuoteAmount
+	m.balance(s.Buyer, s.BaseAsset).Available += s.BaseAmount
+	m.balance(s.Seller, s.BaseAsset).Reserved -= s.BaseAmount
+	m.balance(s.Seller, s.QuoteAsset).Available += s.QuoteAmount
+	m.chargeFee(s.Buyer, s.BuyerFeeAsset, s.BuyerFee, s.BuyerFeeAsset == s.QuoteAsset, s.FeeAccount)
+	m.chargeFee(s.Seller, s.SellerFeeAsset, s.SellerFee, s.SellerFeeAsset == s.BaseAsset, s.FeeAccount)
+}
+
+// chargeFee moves a fee from the user to the fee account. Caller must hold the lock.
+func (m *defaultInMemoryAccountManager) chargeFee(userID, asset string, fee int64, delivered bool, feeAccount string) {
+	if fee == 0 {
+		return
+	}
+	b := m.balance(userID, asset)
+	if fee > 0 && delivered {
+		b.Reserved -= fee
+	} else {
+		b.Available -= fee
+	}
+	m.balance(feeAccount, asset).Available += fee
+}
+
+// WithAccountManager enables pre-trade fund reservation and per-fill settlement
+func WithAccountManager(am AccountManager) Option {
+	return func(me *MatchingEngine) {
+		me.accounts = am
+	}
+}
+
+// reservation tracks the funds still held for a live order
+type reservation struct {
+	userID string
+	asset  string
+	amount int64
+}
+
+// requiredReserve returns the funds a limit order needs for its remaining size
+func (me *MatchingEngine) requiredReserve(order *orderbook.Order) (string, int64, error) {
+	if order.Side == orderbook.Sell {
+		return me.instrument.BaseAsset, order.Size, nil
+	}
+	amount, ok := order.QuoteSize, true
+	if order.QuoteSize == 0 {
+		amount, ok = me.notionalCeil(order.Price, order.Size)
+	}
+	if !ok {
+		return "", 0, ErrNotionalOverflow
+	}
+	if me.buyerPaysFeeFromReserve() {
+		amount += ComputeFee(amount, me.fees.maxChargeRate())
+	}
+	return me.instrument.QuoteAsset, amount, nil
+}
+
+// reserveFunds holds the funds an incoming order may spend.
+// Market buys sized in base have no price bound, so the whole available quote balance is held
+// and the fill size is capped by it during matching. Quote-sized buys hold their quote amount.
+func (me *MatchingEngine) reserveFunds(order *orderbook.Order) error {
+	if me.accounts == nil {
+		return nil
+	}
+	var asset string
+	var amount int64
+	if order.Type == orderbook.Market && order.Side == orderbook.Buy && order.QuoteSize == 0 {
+		asset = me.instrument.QuoteAsset
+		amount = me.accounts.Balance(order.UserID, asset).Available
+		if amount <= 0 {
+			return ErrInsufficientFunds
+		}
+	} else {
+		var err error
+		asset, amount, err = me.requiredReserve(order)
+		if err != nil {
+			return err
+		}
+	}
+	if err := me.accounts.Reserve(order.UserID, asset, amount); err != nil {
+		return err
+	}
+	me.reservations[order.ID] = &reservation{userID: order.UserID, asset: asset, amount: amount}
+	return nil
+}
+
+// marketBuyBudget returns the quote a market buy may still spend on fills, if it is funds-capped
+func (me *MatchingEngine) marketBuyBudget(order *orderbook.Order) (int64, bool) {
+	if me.accounts == nil || order.Type != orderbook.Market || order.Side != orderbook.Buy {
+		return 0, false
+	}
+	res, ok := me.reservations[order.ID]
+	if !ok {
+		return 0, false
+	}
+	budget := res.amount
+	if me.buyerPaysFeeFromReserve() {
+		// Leave room for the taker fee: budget * (1 + rate) never exceeds the reservation
+		if rate := me.fees.Tier(order.UserID, order.Timestamp).TakerRate; rate > 0 {
+			budget, _ = mulDiv(budget, FeeRateScale, FeeRateScale+rate)
+		}
+	}
+	return budget, true
+}
+
+// affordableSize caps a fill so that a market buy never spends more than it reserved,
+// rounded down to the lot size
+func (me *MatchingEngine) affordableSize(taker *orderbook.Order, price, size int64) int64 {
+	if price <= 0 {
+		return size
+	}
+	budget, ok := me.marketBuyBudget(taker)
+	if !ok {
+		return size
+	}
+	maxSize, ok := mulDiv(budget, me.instrument.QuantityScale, price)
+	if ok && maxSize < size {
+		return me.roundLot(maxSize)
+	}
+	return size
+}
+
+// settleFill transfers balances and fees between maker and taker for a single fill
+// and records it in the ledger
+func (me *MatchingEngine) settleFill(ev *orderbook.MatchEvent, maker, taker *orderbook.Order) {
+	if me.accounts == nil && me.ledger == nil {
+		return
+	}
+	s, buyer, seller := me.settlement(ev, maker, taker)
+	me.postFill(ev, s)
+	if me.accounts == nil {
+		return
+	}
+	me.accounts.Settle(s)
+
+	if res, ok := me.reservations[buyer.ID]; ok {
+		res.amount -= s.QuoteAmount
+		if s.BuyerFee > 0 && s.BuyerFeeAsset == s.QuoteAsset {
+			res.amount -= s.BuyerFee
+		}
+	}
+	if res, ok := me.reservations[seller.ID]; ok {
+		res.amount -= ev.Size
+	}
+}
+
+// settlement builds the balance movements of a fill and returns the buying and selling orders
+func (me *MatchingEngine) settlement(ev *orderbook.MatchEvent, maker, taker *orderbook.Order) (Settlement, *orderbook.Order, *orderbook.Order) {
+	quote, _ := me.notional(ev.Price, ev.Size)
+	s := Settlement{
+		Buyer:       taker.UserID,
+		Seller:      maker.UserID,
+		BaseAsset:   me.instrument.BaseAsset,
+		QuoteAsset:  me.instrument.QuoteAsset,
+		BaseAmount:  ev.Size,
+		QuoteAmount: quote,
+
+		BuyerFee:       ev.TakerFee,
+		BuyerFeeAsset:  ev.TakerFeeAsset,
+		SellerFee:      ev.MakerFee,
+		SellerFeeAsset: ev.MakerFeeAsset,
+	}
+	buyer, seller := taker, maker
+	if taker.Side == orderbook.Sell {
+		buyer, seller = maker, taker
+		s.Buyer, s.Seller = maker.UserID, taker.UserID
+		s.BuyerFee, s.BuyerFeeAsset = ev.MakerFee, ev.MakerFeeAsset
+		s.SellerFee, s.SellerFeeAsset = ev.TakerFee, ev.TakerFeeAsset
+	}
+	if me.fees != nil {
+		s.FeeAccount = me.fees.schedule.FeeAccount
+	}
+	return s, buyer, seller
+}
+
+// trimReservation releases funds held beyond what a resting order still needs
+// (e.g. after a limit buy was filled at a better price than its limit)
+func (me *MatchingEngine) trimReservation(order *orderbook.Order) {
+	res, ok := me.reservations[order.ID]
+	if !ok {
+		return
+	}
+	_, required, err := me.requiredReserve(order)
+	if err != nil || res.amount <= required {
+		return
+	}
+	me.accounts.Release(res.userID, res.asset, res.amount-required)
+	res.amount = required
+}
+
+// releaseFunds returns whatever is still reserved for an order that left the book
+func (me *MatchingEngine) releaseFunds(orderID uint64) {
+	res, ok := me.reservations[orderID]
+	if !ok {
+		return
+	}
+	delete(me.reservations, orderID)
+	me.accounts.Release(res.userID, res.asset, res.amount)
+}
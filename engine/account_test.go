@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func newFundedEngine(t *testing.T) (*MatchingEngine, AccountManager) {
+	t.Helper()
+	am := NewDefaultInMemoryAccountManager()
+	me := NewMatchingEngine(
+		WithInstrument(Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", QuantityScale: 1}),
+		WithAccountManager(am),
+	)
+	am.Deposit("alice", "BTC", 100)
+	am.Deposit("bob", "USD", 10000)
+	return me, am
+}
+
+func TestAccounts_ReserveSettleRelease(t *testing.T) {
+	me, am := newFundedEngine(t)
+
+	// Alice sells 10 @ 100 -> reserves 10 BTC
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1}); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if b := am.Balance("alice", "BTC"); b.Available != 90 || b.Reserved != 10 {
+		t.Errorf("Alice BTC after sell: %+v", b)
+	}
+
+	// Bob buys 15 @ 110 -> reserves 1650, fills 10 @ 100, rests 5 @ 110
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "bob", Price: 110, Size: 15, Side: orderbook.Buy, Timestamp: 2}); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if b := am.Balance("alice", "BTC"); b.Available != 90 || b.Reserved != 0 {
+		t.Errorf("Alice BTC after fill: %+v", b)
+	}
+	if b := am.Balance("alice", "USD"); b.Available != 1000 {
+		t.Errorf("Alice USD after fill: %+v", b)
+	}
+	if b := am.Balance("bob", "BTC"); b.Available != 10 {
+		t.Errorf("Bob BTC after fill: %+v", b)
+	}
+	// Price improvement is released, only the resting 5 @ 110 stays reserved
+	if b := am.Balance("bob", "USD"); b.Available != 8450 || b.Reserved != 550 {
+		t.Errorf("Bob USD after fill: %+v", b)
+	}
+
+	// Cancel releases the remaining reservation
+	if err := me.CancelOrder(2); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if b := am.Balance("bob", "USD"); b.Available != 9000 || b.Reserved != 0 {
+		t.Errorf("Bob USD after cancel: %+v", b)
+	}
+}
+
+func TestAccounts_InsufficientFunds(t *testing.T) {
+	me, am := newFundedEngine(t)
+
+	_, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "bob", Price: 1000, Size: 11, Side: orderbook.Buy, Timestamp: 1})
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got %v", err)
+	}
+	if _, ok := me.OrderBook.GetOrder(1); ok {
+		t.Errorf("Rejected order should not rest")
+	}
+	if b := am.Balance("bob", "USD"); b.Available != 10000 || b.Reserved != 0 {
+		t.Errorf("Rejected order should not hold funds: %+v", b)
+	}
+
+	_, err = me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "carol", Type: orderbook.Market, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds for unfunded market buy, got %v", err)
+	}
+}
+
+func TestAccounts_MarketBuyCappedByBalance(t *testing.T) {
+	me, am := newFundedEngine(t)
+	am.Deposit("carol", "USD", 250)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+
+	// Carol can only afford 2 units at 100
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "carol", Type: orderbook.Market, Size: 5, Side: orderbook.Buy, Timestamp: 2})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Size != 2 {
+		t.Fatalf("Expected a single fill of 2, got %v", events)
+	}
+	if b := am.Balance("carol", "USD"); b.Available != 50 || b.Reserved != 0 {
+		t.Errorf("Carol USD: %+v", b)
+	}
+	if b := am.Balance("carol", "BTC"); b.Available != 2 {
+		t.Errorf("Carol BTC: %+v", b)
+	}
+	if o, ok := me.OrderBook.GetOrder(1); !ok || o.Size != 8 {
+		t.Errorf("Maker should have 8 left")
+	}
+}
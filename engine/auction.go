@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sort"
+)
+
+// IndicativeUncross is the price and volume an auction would execute at if it ended now
+type IndicativeUncross struct {
+	Price     int64 `json:"price"`     // 0 if the book does not cross
+	Volume    int64 `json:"volume"`    // Executable size at Price
+	Imbalance int64 `json:"imbalance"` // Unmatched size at Price: positive for buy surplus, negative for sell surplus
+}
+
+// StartAuction switches the book into a call auction: orders accumulate without matching
+func (me *MatchingEngine) StartAuction() error {
+	if me.state == TradingAuction {
+		return ErrAuctionInProgress
+	}
+	_, err := me.transition(TradingAuction, "admin", me.clock.Now())
+	return err
+}
+
+// InAuction reports whether the book is in a call auction
+func (me *MatchingEngine) InAuction() bool {
+	return me.state == TradingAuction
+}
+
+// EndAuction uncrosses the book at the single clearing price and returns to continuous matching.
+// Stops triggered by the auction prints are executed and their fills returned as well.
+func (me *MatchingEngine) EndAuction() ([]orderbook.MatchEvent, error) {
+	if me.state != TradingAuction {
+		return nil, ErrNoAuction
+	}
+	return me.SetTradingState(TradingContinuous)
+}
+
+// IndicativeUncross computes the clearing price that maximizes executed volume.
+// Ties are broken by (1) the smallest absolute imbalance, (2) market pressure: the highest
+// price if every candidate has a buy surplus, the lowest if every candidate has a sell surplus,
+// (3) the price closest to the last trade price, and finally (4) the lowest price.
+func (me *MatchingEngine) IndicativeUncross() IndicativeUncross {
+	depth := me.OrderBook.GetDepth(max(me.OrderBook.Asks.Len(), me.OrderBook.Bids.Len()))
+	if len(depth.Bids) == 0 || len(depth.Asks) == 0 || depth.Bids[0].Price < depth.Asks[0].Price {
+		return IndicativeUncross{}
+	}
+
+	// Candidate prices are all level prices, ascending
+	seen := make(map[int64]struct{}, len(depth.Bids)+len(depth.Asks))
+	var prices []int64
+	for _, levels := range [][]orderbook.PriceLevel{depth.Bids, depth.Asks} {
+		for _, l := range levels {
+			if _, ok := seen[l.Price]; !ok {
+				seen[l.Price] = struct{}{}
+				prices = append(prices, l.Price)
+			}
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	totalDemand := int64(0)
+	for _, l := range depth.Bids {
+		totalDemand += l.Size
+	}
+
+	// Walk prices upwards: supply accumulates asks <= p, demand drops bids < p
+	candidates := make([]IndicativeUncross, 0, len(prices))
+	supply, demand := int64(0), totalDemand
+	ai, bi := 0, len(depth.Bids)-1
+	for _, p := range prices {
+		for ai < len(depth.Asks) && depth.Asks[ai].Price <= p {
+			supply += depth.Asks[ai].Size
+			ai++
+		}
+		for bi >= 0 && depth.Bids[bi].Price < p {
+			demand -= depth.Bids[bi].Size
+			bi--
+		}
+		candidates = append(candidates, IndicativeUncross{Price: p, Volume: min(supply, demand), Imbalance: demand - supply})
+	}
+
+	return me.selectClearingPrice(candidates)
+}
+
+// selectClearingPrice applies the volume and tie-break rules to candidate prices (ascending)
+func (me *MatchingEngine) selectClearingPrice(candidates []IndicativeUncross) IndicativeUncross {
+	abs := func(v int64) int64 {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	filter := func(keep func(c IndicativeUncross) bool) {
+		out := candidates[:0]
+		for _, c := range candidates {
+			if keep(c) {
+				out = append(out, c)
+			}
+		}
+		candidates = out
+	}
+
+	// 1. Maximum executable volume
+	best := int64(0)
+	for _, c := range candidates {
+		best = max(best, c.Volume)
+	}
+	if best == 0 {
+		return IndicativeUncross{}
+	}
+	filter(func(c IndicativeUncross) bool { return c.Volume == best })
+
+	// 2. Minimum absolute imbalance
+	minImb := abs(candidates[0].Imbalance)
+	for _, c := range candidates {
+		minImb = min(minImb, abs(c.Imbalance))
+	}
+	filter(func(c IndicativeUncross) bool { return abs(c.Imbalance) == minImb })
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	// 3. Market pressure
+	allBuy, allSell := true, true
+	for _, c := range candidates {
+		allBuy = allBuy && c.Imbalance > 0
+		allSell = allSell && c.Imbalance < 0
+	}
+	if allBuy {
+		return candidates[len(candidates)-1]
+	}
+	if allSell {
+		return candidates[0]
+	}
+
+	// 4. Closest to reference price, lowest price on equal distance
+	result := candidates[0]
+	if ref := me.lastTradePrice; ref > 0 {
+		for _, c := range candidates[1:] {
+			if abs(c.Price-ref) < abs(result.Price-ref) {
+				result = c
+			}
+		}
+	}
+	return result
+}
+
+// uncross executes the auction at the clearing price, stamping fills with now. Orders are allocated by price then time
+// priority on each side; the order that arrived later is reported as the taker of each fill.
+func (me *MatchingEngine) uncross(ind IndicativeUncross, now int64) []orderbook.MatchEvent {
+	events := orderbook.GetMatchEventSlice()
+	remaining := ind.Volume
+
+	for remaining > 0 {
+		bid := me.OrderBook.GetBestBid()
+		ask := me.OrderBook.GetBestAsk()
+		if bid == nil || ask == nil || bid.Price < ind.Price || ask.Price > ind.Price {
+			break
+		}
+		size := min(bid.Size, ask.Size, remaining)
+		if o := me.closedReduceOnly(bid, ask); o != nil {
+			// A reduce-only order whose position is already closed leaves the auction
+			report := execReport(o, orderbook.ExecCancelled, now)
+			if me.processCancelOrder(o.ID) == nil {
+				me.emitReports([]orderbook.ExecutionReport{report})
+			}
+			continue
+		}
+		for _, o := range []*orderbook.Order{bid, ask} {
+			if o.ReduceOnly {
+				size = min(size, me.reducibleSize(o))
+			}
+		}
+
+		maker, taker := bid, ask
+		if ask.Timestamp < bid.Timestamp || (ask.Timestamp == bid.Timestamp && ask.ID < bid.ID) {
+			maker, taker = ask, bid
+		}
+		ev := me.newMatchEvent(maker, taker, ind.Price, size, now)
+		me.observeTrade(ev.Price)
+		me.applyFees(&ev, maker, taker)
+		me.settleFill(&ev, maker, taker)
+		me.updatePositions(&ev, maker, taker)
+		me.publishTrade(&ev)
+		me.recordTrade(&ev)
+		events = append(events, ev)
+
+		bid.Size -= size
+		ask.Size -= size
+		remaining -= size
+		me.onFill(bid, size)
+		me.onFill(ask, size)
+		for _, o := range []*orderbook.Order{bid, ask} {
+			if o.Size == 0 {
+				me.OrderBook.RemoveOrder(o.ID)
+				me.finishOrder(o)
+				orderbook.PutOrder(o)
+			}
+		}
+	}
+
+	// Bids that traded below their limit may now hold more funds than they need
+	if bid := me.OrderBook.GetBestBid(); bid != nil {
+		me.trimReservation(bid)
+	}
+	return events
+}
+
+// processAuctionOrder rests an order without matching while the auction is running
+func (me *MatchingEngine) processAuctionOrder(order *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	me.restOrder(order)
+	me.publishIndicative()
+	return orderbook.GetMatchEventSlice(), nil
+}
+
+// publishIndicative sends the current indicative uncross to the market data feed
+func (me *MatchingEngine) publishIndicative() {
+	if me.marketData == nil {
+		return
+	}
+	ind := me.IndicativeUncross()
+	me.publish(MarketDataEvent{Type: MDIndicativeUncross, Indicative: &ind})
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestAuction_Uncross(t *testing.T) {
+	feed := NewDefaultInMemoryMarketDataFeed()
+	me := NewMatchingEngine(WithMarketDataPublisher(feed))
+	if err := me.StartAuction(); err != nil {
+		t.Fatalf("StartAuction failed: %v", err)
+	}
+
+	orders := []*orderbook.Order{
+		{ID: 1, Price: 102, Size: 10, Side: orderbook.Buy, Timestamp: 1},
+		{ID: 2, Price: 101, Size: 10, Side: orderbook.Buy, Timestamp: 2},
+		{ID: 3, Price: 100, Size: 10, Side: orderbook.Buy, Timestamp: 3},
+		{ID: 4, Price: 99, Size: 10, Side: orderbook.Sell, Timestamp: 4},
+		{ID: 5, Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 5},
+		{ID: 6, Price: 101, Size: 15, Side: orderbook.Sell, Timestamp: 6},
+	}
+	for _, o := range orders {
+		events, err := me.PlaceOrder(o)
+		if err != nil {
+			t.Fatalf("PlaceOrder failed: %v", err)
+		}
+		if len(events) != 0 {
+			t.Fatalf("Orders must not match during the auction")
+		}
+	}
+
+	// 100 and 101 both execute 20; 100 leaves the smaller imbalance
+	ind := me.IndicativeUncross()
+	if ind.Price != 100 || ind.Volume != 20 || ind.Imbalance != 10 {
+		t.Errorf("Unexpected indicative uncross: %+v", ind)
+	}
+	published := feed.Drain()
+	// State change, opening indicative, then one update per order
+	if len(published) != len(orders)+2 || published[0].Type != MDTradingState {
+		t.Fatalf("Expected an indicative update per order, got %d events", len(published))
+	}
+	if last := published[len(published)-1]; last.Type != MDIndicativeUncross || *last.Indicative != ind {
+		t.Errorf("Last published indicative mismatch: %+v", last)
+	}
+
+	events, err := me.EndAuction()
+	if err != nil {
+		t.Fatalf("EndAuction failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 fills, got %v", events)
+	}
+	for _, e := range events {
+		if e.Price != 100 || e.Size != 10 {
+			t.Errorf("Fill should be 10 @ 100: %+v", e)
+		}
+	}
+	if events[0].MakerOrderID != 1 || events[0].TakerOrderID != 4 {
+		t.Errorf("Earlier order should be maker: %+v", events[0])
+	}
+	if bid, ask := me.OrderBook.GetBestBid(), me.OrderBook.GetBestAsk(); bid.ID != 3 || ask.ID != 6 || ask.Size != 15 {
+		t.Errorf("Unexpected book after uncross: bid %+v ask %+v", bid, ask)
+	}
+
+	// Back to continuous matching
+	if events, _ := me.PlaceOrder(&orderbook.Order{ID: 7, Price: 101, Size: 5, Side: orderbook.Buy, Timestamp: 7}); len(events) != 1 {
+		t.Errorf("Expected continuous matching after auction")
+	}
+}
+
+func TestAuction_ReferencePriceTieBreak(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 101, Size: 1, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 101, Size: 1, Side: orderbook.Buy, Timestamp: 2})
+
+	me.StartAuction()
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 101, Size: 10, Side: orderbook.Buy, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 99, Size: 10, Side: orderbook.Sell, Timestamp: 4})
+
+	// 99 and 101 both execute 10 with no imbalance: last trade 101 decides
+	if ind := me.IndicativeUncross(); ind.Price != 101 || ind.Volume != 10 {
+		t.Errorf("Unexpected indicative uncross: %+v", ind)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 5, Type: orderbook.Market, Size: 1, Side: orderbook.Buy, Timestamp: 5}); err != ErrMarketOrderInAuction {
+		t.Errorf("Expected ErrMarketOrderInAuction, got %v", err)
+	}
+}
+
+func TestAuction_UncrossTriggersStops(t *testing.T) {
+	me := NewMatchingEngine(WithInitialTradingState(TradingAuction))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Type: orderbook.Stop, StopPrice: 100, Size: 4, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 105, Size: 10, Side: orderbook.Sell, Timestamp: 2})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 105, Size: 5, Side: orderbook.Buy, Timestamp: 3})
+
+	// The print at 105 triggers the buy stop, which lifts the rest of the ask
+	events, err := me.EndAuction()
+	if err != nil || len(events) != 2 || events[1].TakerOrderID != 1 || events[1].Size != 4 {
+		t.Fatalf("Expected the uncross and the triggered stop fill, got %v %v", events, err)
+	}
+}
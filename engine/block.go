@@ -0,0 +1,51 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// BlockResult collects everything the engine did while advancing to a new block
+type BlockResult struct {
+	Height    uint64                      `json:"height"`
+	Timestamp int64                       `json:"timestamp"`
+	Expired   []orderbook.ExecutionReport `json:"expired,omitempty"`
+	Levels    []orderbook.LevelUpdate     `json:"levels,omitempty"`
+	Events    []orderbook.MatchEvent      `json:"events,omitempty"` // Fills from auctions uncrossed by scheduled transitions and funding liquidations
+	Funding   []FundingEvent              `json:"funding,omitempty"`
+}
+
+// BlockHeight returns the height of the last block the engine advanced to
+func (me *MatchingEngine) BlockHeight() uint64 {
+	return me.blockHeight
+}
+
+// BlockTime returns the timestamp of the last block the engine advanced to
+func (me *MatchingEngine) BlockTime() int64 {
+	return me.blockTime
+}
+
+// AdvanceBlock moves the engine to a new block supplied by the sequencer and runs all
+// per-block processing against the block, never the wall clock (Web3 deterministic requirement):
+//  1. orders good till a block height at or below height expire
+//  2. orders good till a time at or before timestamp expire
+//  3. scheduled trading state transitions (and volatility auction ends) due at timestamp apply
+//  4. funding intervals ending at or before timestamp settle, liquidating positions they leave under-margined
+func (me *MatchingEngine) AdvanceBlock(height uint64, timestamp int64) (*BlockResult, error) {
+	if height <= me.blockHeight || timestamp < me.blockTime {
+		return nil, ErrInvalidBlock
+	}
+	me.blockHeight = height
+	me.blockTime = timestamp
+	result := &BlockResult{Height: height, Timestamp: timestamp}
+
+	ids := me.blockExpiries.popDue(me.expirableOrder, int64(height), expireBlock)
+	ids = append(ids, me.expiries.popDue(me.expirableOrder, timestamp, expireAt)...)
+	if len(ids) > 0 {
+		result.Expired, result.Levels = me.expireOrders(ids, timestamp)
+	}
+
+	events, err := me.processSchedule(timestamp)
+	result.Events = events
+	funding, fills := me.processFunding(timestamp)
+	result.Funding = funding
+	result.Events = append(result.Events, fills...)
+	return result, err
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestAdvanceBlock_GoodTillBlock(t *testing.T) {
+	me := NewMatchingEngine()
+	if _, err := me.AdvanceBlock(10, 1000); err != nil {
+		t.Fatalf("AdvanceBlock failed: %v", err)
+	}
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 99, Size: 10, Side: orderbook.Buy, Timestamp: 1000, ExpireBlock: 12})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 99, Size: 5, Side: orderbook.Buy, Timestamp: 1000, ExpireAt: 1500})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 98, Size: 5, Side: orderbook.Buy, Timestamp: 1000})
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, Price: 98, Size: 5, Side: orderbook.Buy, Timestamp: 1000, ExpireBlock: 10}); !errors.Is(err, ErrOrderExpired) {
+		t.Errorf("Expected ErrOrderExpired, got %v", err)
+	}
+
+	res, _ := me.AdvanceBlock(11, 1100)
+	if len(res.Expired) != 0 {
+		t.Fatalf("Nothing should expire at block 11, got %v", res.Expired)
+	}
+
+	res, _ = me.AdvanceBlock(12, 1200)
+	if len(res.Expired) != 1 || res.Expired[0].OrderID != 1 {
+		t.Fatalf("Expected order 1 expired at block 12, got %v", res.Expired)
+	}
+	if len(res.Levels) != 1 || res.Levels[0].Size != 5 {
+		t.Errorf("Unexpected level updates: %v", res.Levels)
+	}
+
+	res, _ = me.AdvanceBlock(13, 1500)
+	if len(res.Expired) != 1 || res.Expired[0].OrderID != 2 {
+		t.Fatalf("Expected order 2 expired at block time 1500, got %v", res.Expired)
+	}
+
+	if _, err := me.AdvanceBlock(13, 1600); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for repeated height, got %v", err)
+	}
+	if _, err := me.AdvanceBlock(14, 1400); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for timestamp going back, got %v", err)
+	}
+	if me.BlockHeight() != 13 {
+		t.Errorf("Unexpected height %d", me.BlockHeight())
+	}
+}
+
+func TestAdvanceBlock_Schedule(t *testing.T) {
+	me := NewMatchingEngine(
+		WithInitialTradingState(TradingPreOpen),
+		WithTradingSchedule([]ScheduledTransition{{At: 100, State: TradingContinuous}}),
+	)
+	me.AdvanceBlock(1, 99)
+	if me.TradingState() != TradingPreOpen {
+		t.Fatalf("Market should not open before block time 100")
+	}
+	me.AdvanceBlock(2, 100)
+	if me.TradingState() != TradingContinuous {
+		t.Errorf("Market should open at block time 100, got %v", me.TradingState())
+	}
+}
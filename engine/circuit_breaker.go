@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"math"
+	"orderbook-matching-engine/orderbook"
+	"time"
+)
+
+// CircuitBreakerConfig configures volatility interruptions.
+// A taker that would trade outside either band stops matching at the band and the book
+// enters a volatility auction for AuctionDuration before resuming continuous trading.
+type CircuitBreakerConfig struct {
+	// StaticReferencePrice anchors the static band (e.g. previous close); it is reset
+	// to the clearing price of every auction. 0 disables the static band until an auction prints.
+	StaticReferencePrice int64
+	// StaticBandBps is the static band width around the static reference (0 disables)
+	StaticBandBps int64
+	// DynamicBandBps is the dynamic band width around the last trade price (0 disables)
+	DynamicBandBps int64
+	// AuctionDuration is how long the volatility auction lasts
+	AuctionDuration time.Duration
+}
+
+// WithCircuitBreaker enables static and dynamic volatility bands
+func WithCircuitBreaker(cfg CircuitBreakerConfig) Option {
+	return func(me *MatchingEngine) {
+		me.breaker = &cfg
+		me.staticReference = cfg.StaticReferencePrice
+	}
+}
+
+// StaticReferencePrice returns the current anchor of the static band
+func (me *MatchingEngine) StaticReferencePrice() int64 {
+	return me.staticReference
+}
+
+// VolatilityAuctionEnd returns when the running volatility auction ends, or 0 if none is running
+func (me *MatchingEngine) VolatilityAuctionEnd() int64 {
+	return me.volatilityAuctionEnd
+}
+
+// priceBands returns the [low, high] range takers may currently trade in
+func (me *MatchingEngine) priceBands() (int64, int64) {
+	low, high := int64(0), int64(math.MaxInt64)
+	if me.breaker == nil {
+		return low, high
+	}
+	narrow := func(ref, bps int64) {
+		if ref <= 0 || bps <= 0 {
+			return
+		}
+		low = max(low, bandPrice(ref, bps, orderbook.Sell))
+		high = min(high, bandPrice(ref, bps, orderbook.Buy))
+	}
+	narrow(me.staticReference, me.breaker.StaticBandBps)
+	narrow(me.lastTradePrice, me.breaker.DynamicBandBps)
+	return low, high
+}
+
+// tripCircuitBreaker halts continuous trading and starts a volatility auction
+func (me *MatchingEngine) tripCircuitBreaker(now int64) {
+	if _, err := me.transition(TradingAuction, "volatility", now); err != nil {
+		return
+	}
+	me.volatilityAuctionEnd = now + int64(me.breaker.AuctionDuration)
+}
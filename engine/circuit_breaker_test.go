@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_VolatilityAuction(t *testing.T) {
+	clock := NewManualClock(0)
+	me := NewMatchingEngine(WithClock(clock), WithCircuitBreaker(CircuitBreakerConfig{
+		StaticReferencePrice: 100,
+		StaticBandBps:        2000, // 20%
+		DynamicBandBps:       500,  // 5%
+		AuctionDuration:      time.Minute,
+	}))
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 104, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 110, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+
+	// First trade at 100 sets the dynamic reference
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 100, Size: 5, Side: orderbook.Buy, Timestamp: 2})
+
+	// Sweep: 100 and 104 are inside the 5% dynamic band, 110 is not
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 5, Price: 110, Size: 25, Side: orderbook.Buy, Timestamp: 3})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 2 || events[1].Price != 104 {
+		t.Fatalf("Matching should stop at the band, got %v", events)
+	}
+	if me.TradingState() != TradingAuction {
+		t.Fatalf("Expected volatility auction, got %v", me.TradingState())
+	}
+	if me.VolatilityAuctionEnd() != 3+int64(time.Minute) {
+		t.Errorf("Unexpected auction end: %d", me.VolatilityAuctionEnd())
+	}
+	// Remainder rests and takes part in the auction
+	if o, ok := me.OrderBook.GetOrder(5); !ok || o.Size != 10 {
+		t.Fatalf("Taker remainder should rest with size 10")
+	}
+	if ind := me.IndicativeUncross(); ind.Price != 110 || ind.Volume != 10 {
+		t.Errorf("Unexpected indicative uncross: %+v", ind)
+	}
+
+	// Too early: still in auction
+	clock.Set(int64(30 * time.Second))
+	me.ProcessSchedule()
+	if me.TradingState() != TradingAuction {
+		t.Fatalf("Auction should still be running")
+	}
+
+	clock.Set(3 + int64(time.Minute))
+	events, _ = me.ProcessSchedule()
+	if len(events) != 1 || events[0].Price != 110 || events[0].Size != 10 {
+		t.Fatalf("Expected auction uncross at 110, got %v", events)
+	}
+	if me.TradingState() != TradingContinuous {
+		t.Errorf("Expected continuous trading to resume")
+	}
+	if me.StaticReferencePrice() != 110 {
+		t.Errorf("Static reference should move to the auction price, got %d", me.StaticReferencePrice())
+	}
+}
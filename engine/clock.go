@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock supplies the engine's notion of current time in Unix nanoseconds.
+// Inject a ManualClock (or a block-time source) for deterministic execution.
+type Clock interface {
+	Now() int64
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() int64 {
+	return time.Now().UnixNano()
+}
+
+// SystemClock returns a clock backed by local wall time (non-deterministic)
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// ManualClock is a clock that only moves when told to, for tests and replays
+type ManualClock struct {
+	mu  sync.RWMutex
+	now int64
+}
+
+// NewManualClock creates a manual clock starting at the given Unix nanoseconds
+func NewManualClock(start int64) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Set moves the clock to an absolute time
+func (c *ManualClock) Set(now int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now += int64(d)
+}
+
+// WithClock sets the clock used for default timestamps and time-driven limits
+func WithClock(c Clock) Option {
+	return func(me *MatchingEngine) {
+		me.clock = c
+	}
+}
@@ -0,0 +1,199 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// darkBook holds non-displayed orders pegged to the lit midpoint. They never appear in the
+// depth or on the market data feed and only trade against each other.
+type darkBook struct {
+	buys   []*orderbook.Order // Time priority
+	sells  []*orderbook.Order // Time priority
+	orders map[uint64]*orderbook.Order
+}
+
+func newDarkBook() darkBook {
+	return darkBook{orders: make(map[uint64]*orderbook.Order)}
+}
+
+func (b *darkBook) add(order *orderbook.Order) {
+	if order.Side == orderbook.Buy {
+		b.buys = append(b.buys, order)
+	} else {
+		b.sells = append(b.sells, order)
+	}
+	b.orders[order.ID] = order
+}
+
+func (b *darkBook) remove(orderID uint64) (*orderbook.Order, bool) {
+	order, ok := b.orders[orderID]
+	if !ok {
+		return nil, false
+	}
+	delete(b.orders, orderID)
+	if order.Side == orderbook.Buy {
+		b.buys = deleteOrder(b.buys, orderID)
+	} else {
+		b.sells = deleteOrder(b.sells, orderID)
+	}
+	return order, true
+}
+
+// PlaceDarkOrder places a hidden midpoint order. Price is the worst price the order accepts
+// and MinQty the minimum size of each execution. Dark orders match only against each other,
+// at the lit midpoint, while the book is in continuous trading. They pass the same entry checks
+// as lit orders, count as open orders of their user and are cancelled by mass cancels, session
+// loss and expiry like resting lit orders. Reduce-only dark orders never execute more than
+// their owner's position and are resized or cancelled as it shrinks.
+func (me *MatchingEngine) PlaceDarkOrder(order *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	if order.ID == 0 {
+		return nil, ErrOrderIDNotSet
+	}
+	if me.reservedAccount(order.UserID) {
+		return nil, ErrReservedAccount
+	}
+	if order.Size <= 0 || order.MinQty < 0 {
+		return nil, ErrInvalidOrderSize
+	}
+	if order.Type != orderbook.Limit || order.PegType != orderbook.PegNone {
+		return nil, ErrInvalidDarkOrder
+	}
+	if lot := me.instrument.LotSize; lot > 1 && order.Size%lot != 0 {
+		return nil, ErrInvalidLotSize
+	}
+	if order.Price <= 0 {
+		return nil, ErrInvalidLimitOrderPrice
+	}
+	if _, ok := me.dark.orders[order.ID]; ok {
+		return nil, ErrInvalidDarkOrder
+	}
+	if !me.state.acceptsOrders() {
+		return nil, ErrPlaceNotAllowed
+	}
+	if order.Timestamp == 0 {
+		order.Timestamp = me.clock.Now()
+	}
+	if err := me.checkExpiry(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkSession(order); err != nil {
+		return nil, err
+	}
+	if err := me.throttle(order.UserID, false); err != nil {
+		return nil, err
+	}
+	if err := me.preTradeChecks(order); err != nil {
+		return nil, err
+	}
+
+	me.dark.add(order)
+	me.trackOpenOrder(order)
+	me.linkSessionOrder(order)
+	me.indexExpiry(order)
+	return me.processContingent(orderbook.GetMatchEventSlice(), order.Timestamp), nil
+}
+
+// CancelDarkOrder cancels a resting dark order
+func (me *MatchingEngine) CancelDarkOrder(orderID uint64) error {
+	order, ok := me.dark.orders[orderID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if !me.state.acceptsCancels() {
+		return ErrCancelNotAllowed
+	}
+	if err := me.throttle(order.UserID, true); err != nil {
+		return err
+	}
+	return me.processCancelDarkOrder(orderID)
+}
+
+// processCancelDarkOrder is the internal dark cancel logic
+func (me *MatchingEngine) processCancelDarkOrder(orderID uint64) error {
+	order, ok := me.dark.remove(orderID)
+	if !ok {
+		return ErrOrderNotFound
+	}
+	me.finishOrder(order)
+	return nil
+}
+
+// liveOrder returns a resting order of the lit or the dark book
+func (me *MatchingEngine) liveOrder(orderID uint64) (*orderbook.Order, bool) {
+	if order, ok := me.OrderBook.GetOrder(orderID); ok {
+		return order, true
+	}
+	return me.GetDarkOrder(orderID)
+}
+
+// GetDarkOrder returns a resting dark order
+func (me *MatchingEngine) GetDarkOrder(orderID uint64) (*orderbook.Order, bool) {
+	order, ok := me.dark.orders[orderID]
+	return order, ok
+}
+
+// litMidpoint returns the midpoint of the lit book rounded down, or 0 if a side is empty
+func (me *MatchingEngine) litMidpoint() int64 {
+	bid, ask := me.OrderBook.GetBestBid(), me.OrderBook.GetBestAsk()
+	if bid == nil || ask == nil {
+		return 0
+	}
+	return bid.Price + (ask.Price-bid.Price)/2
+}
+
+// crossDark matches every dark order that can trade at the current lit midpoint.
+// Buys are taken in time priority, each trading against sells in time priority; a pair
+// trades only if the fill satisfies both minimum quantities. The later order is the taker.
+// Dark prints do not move the last trade price.
+func (me *MatchingEngine) crossDark(now int64) []orderbook.MatchEvent {
+	mid := me.litMidpoint()
+	if mid <= 0 {
+		return nil
+	}
+	var events []orderbook.MatchEvent
+	for i := 0; i < len(me.dark.buys); i++ {
+		buy := me.dark.buys[i]
+		if buy.Price < mid {
+			continue
+		}
+		for j := 0; j < len(me.dark.sells) && buy.Size > 0; j++ {
+			sell := me.dark.sells[j]
+			size := min(buy.Size, sell.Size)
+			if buy.ReduceOnly {
+				size = min(size, me.reducibleSize(buy))
+			}
+			if sell.ReduceOnly {
+				size = min(size, me.reducibleSize(sell))
+			}
+			if size == 0 || sell.Price > mid || size < min(buy.MinQty, buy.Size) || size < min(sell.MinQty, sell.Size) {
+				continue
+			}
+
+			maker, taker := buy, sell
+			if sell.Timestamp < buy.Timestamp || (sell.Timestamp == buy.Timestamp && sell.ID < buy.ID) {
+				maker, taker = sell, buy
+			}
+			ev := me.newMatchEvent(maker, taker, mid, size, now)
+			me.applyFees(&ev, maker, taker)
+			me.settleFill(&ev, maker, taker)
+			me.updatePositions(&ev, maker, taker)
+			me.recordTrade(&ev)
+			events = append(events, ev)
+
+			buy.Size -= size
+			sell.Size -= size
+			if sell.Size == 0 {
+				me.dark.remove(sell.ID)
+				me.finishOrder(sell)
+				j--
+			}
+		}
+		if buy.Size == 0 {
+			me.dark.remove(buy.ID)
+			me.finishOrder(buy)
+			i--
+		} else {
+			// Filled below its limit: keep only what the rest needs
+			me.trimReservation(buy)
+		}
+	}
+	return events
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestDarkPool_MidpointMatching(t *testing.T) {
+	feed := NewDefaultInMemoryMarketDataFeed()
+	me := NewMatchingEngine(WithMarketDataPublisher(feed))
+
+	// No lit midpoint yet: dark orders wait
+	me.PlaceDarkOrder(&orderbook.Order{ID: 1, Price: 110, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+	events, _ := me.PlaceDarkOrder(&orderbook.Order{ID: 2, Price: 90, Size: 4, Side: orderbook.Sell, Timestamp: 2})
+	if len(events) != 0 {
+		t.Fatalf("Dark orders need a lit midpoint, got %v", events)
+	}
+	if depth := me.GetDepth(10); len(depth.Bids) != 0 || len(depth.Asks) != 0 {
+		t.Fatalf("Dark orders must not show in the depth")
+	}
+
+	// Lit quotes create a midpoint of 100, which crosses the dark orders
+	me.PlaceOrder(&orderbook.Order{ID: 10, Price: 98, Size: 1, Side: orderbook.Buy, Timestamp: 3})
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 11, Price: 102, Size: 1, Side: orderbook.Sell, Timestamp: 4})
+	if len(events) != 1 || events[0].Price != 100 || events[0].Size != 4 || events[0].MakerOrderID != 1 || events[0].TakerOrderID != 2 {
+		t.Fatalf("Expected dark cross of 4 at 100, got %v", events)
+	}
+	if o, ok := me.GetDarkOrder(1); !ok || o.Size != 6 {
+		t.Fatalf("Dark buy should have 6 left")
+	}
+	if me.LastTradePrice() != 0 {
+		t.Errorf("Dark prints must not move the last trade price")
+	}
+	for _, ev := range feed.Drain() {
+		if ev.Type == MDTrade {
+			t.Errorf("Dark prints must not be published: %v", ev)
+		}
+	}
+
+	// Sell limit above the midpoint does not trade
+	events, _ = me.PlaceDarkOrder(&orderbook.Order{ID: 3, Price: 101, Size: 5, Side: orderbook.Sell, Timestamp: 5})
+	if len(events) != 0 {
+		t.Fatalf("Sell limited at 101 must not trade at 100, got %v", events)
+	}
+	if err := me.CancelDarkOrder(3); err != nil {
+		t.Fatalf("CancelDarkOrder failed: %v", err)
+	}
+}
+
+func TestDarkPool_MinQty(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 10, Price: 98, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 11, Price: 102, Size: 1, Side: orderbook.Sell, Timestamp: 1})
+
+	me.PlaceDarkOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, MinQty: 5, Side: orderbook.Buy, Timestamp: 2})
+	events, _ := me.PlaceDarkOrder(&orderbook.Order{ID: 2, Price: 100, Size: 3, Side: orderbook.Sell, Timestamp: 3})
+	if len(events) != 0 {
+		t.Fatalf("Fill of 3 violates the buyer's minimum of 5, got %v", events)
+	}
+
+	// The next sell is large enough; the small one keeps waiting
+	events, _ = me.PlaceDarkOrder(&orderbook.Order{ID: 3, Price: 100, Size: 6, Side: orderbook.Sell, Timestamp: 4})
+	if len(events) != 1 || events[0].TakerOrderID != 3 || events[0].Size != 6 {
+		t.Fatalf("Expected fill of 6 against order 3, got %v", events)
+	}
+
+	// Remaining 4 is below the minimum, so a fill of the whole remainder is allowed
+	events, _ = me.PlaceDarkOrder(&orderbook.Order{ID: 4, Price: 100, Size: 4, Side: orderbook.Sell, Timestamp: 5})
+	if len(events) != 1 || events[0].TakerOrderID != 4 || events[0].Size != 4 {
+		t.Fatalf("Expected fill of the remaining 4, got %v", events)
+	}
+	if _, ok := me.GetDarkOrder(2); !ok {
+		t.Errorf("Order 2 should still be waiting")
+	}
+}
+
+func TestDarkPool_SharedEntryChecksAndCancels(t *testing.T) {
+	clock := NewManualClock(1)
+	me := NewMatchingEngine(WithClock(clock), WithLimits(LimitConfig{MaxOpenOrders: 2}), WithSessions(SessionConfig{}))
+	me.OpenSession("s1", "mm")
+
+	if _, err := me.PlaceDarkOrder(&orderbook.Order{ID: 1, UserID: "other", SessionID: "s1", Price: 100, Size: 1, Side: orderbook.Buy}); !errors.Is(err, ErrSessionUserMismatch) {
+		t.Fatalf("Expected ErrSessionUserMismatch, got %v", err)
+	}
+	me.PlaceDarkOrder(&orderbook.Order{ID: 2, UserID: "mm", SessionID: "s1", Price: 100, Size: 1, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "mm", Price: 90, Size: 1, Side: orderbook.Buy})
+	if _, err := me.PlaceDarkOrder(&orderbook.Order{ID: 4, UserID: "mm", Price: 100, Size: 1, Side: orderbook.Buy}); !errors.Is(err, ErrTooManyOpenOrders) {
+		t.Fatalf("Dark orders should count as open orders, got %v", err)
+	}
+
+	// Closing the session cancels its dark order
+	res, _ := me.CloseSession("s1")
+	if len(res.Cancelled) != 1 || res.Cancelled[0].ID != 2 {
+		t.Fatalf("Expected dark order 2 cancelled with the session, got %v", res.Cancelled)
+	}
+	if _, ok := me.GetDarkOrder(2); ok {
+		t.Fatalf("Dark order should be removed")
+	}
+
+	// Expiry and mass cancels reach the dark book
+	me.PlaceDarkOrder(&orderbook.Order{ID: 5, UserID: "mm", Price: 100, Size: 1, Side: orderbook.Sell, Timestamp: 1, ExpireAt: 10})
+	if reports, _ := me.ExpireOrders(10); len(reports) != 1 || reports[0].OrderID != 5 {
+		t.Fatalf("Expected dark order 5 expired, got %v", reports)
+	}
+	me.PlaceDarkOrder(&orderbook.Order{ID: 6, UserID: "mm", Price: 100, Size: 1, Side: orderbook.Sell})
+	if res, _ := me.CancelUserOrders("mm"); len(res.Cancelled) != 2 || me.OpenOrderCount("mm") != 0 {
+		t.Fatalf("Expected lit and dark orders cancelled, got %v", res.Cancelled)
+	}
+}
+
+func TestDarkPool_ReduceOnly(t *testing.T) {
+	me := NewMatchingEngine()
+	fill(me, 1, "alice", "bob", 100, 5)
+	if _, err := me.PlaceDarkOrder(&orderbook.Order{ID: 10, UserID: "alice", Price: 90, Size: 5, ReduceOnly: true, Side: orderbook.Buy}); !errors.Is(err, ErrReduceOnly) {
+		t.Fatalf("Expected ErrReduceOnly for a dark buy adding to a long, got %v", err)
+	}
+	me.PlaceDarkOrder(&orderbook.Order{ID: 11, UserID: "alice", Price: 90, Size: 5, ReduceOnly: true, Side: orderbook.Sell, Timestamp: 11})
+	me.PlaceDarkOrder(&orderbook.Order{ID: 12, UserID: "alice", Price: 90, Size: 5, ReduceOnly: true, Side: orderbook.Sell, Timestamp: 12})
+
+	// A lit sell reduces the position to 2: resting dark reduce-only orders follow it
+	fill(me, 20, "carol", "alice", 100, 3)
+	if o, _ := me.GetDarkOrder(11); o.Size != 2 {
+		t.Fatalf("Expected dark reduce-only order resized to 2, got %d", o.Size)
+	}
+
+	// The dark cross closes the position and cancels the order with nothing left to reduce
+	me.PlaceOrder(&orderbook.Order{ID: 30, UserID: "mm", Price: 98, Size: 1, Side: orderbook.Buy, Timestamp: 30})
+	me.PlaceOrder(&orderbook.Order{ID: 31, UserID: "mm", Price: 102, Size: 1, Side: orderbook.Sell, Timestamp: 31})
+	events, _ := me.PlaceDarkOrder(&orderbook.Order{ID: 40, UserID: "dave", Price: 110, Size: 10, Side: orderbook.Buy, Timestamp: 40})
+	if len(events) != 1 || events[0].MakerOrderID != 11 || events[0].Size != 2 {
+		t.Fatalf("Expected a single dark fill of 2 against order 11, got %v", events)
+	}
+	if p := me.Position("alice"); p.Size != 0 {
+		t.Fatalf("Reduce-only dark orders must not flip the position, got %+v", p)
+	}
+	if _, ok := me.GetDarkOrder(12); ok {
+		t.Fatalf("Dark reduce-only order with nothing left to reduce should be cancelled")
+	}
+	if o, _ := me.GetDarkOrder(40); o.Size != 8 {
+		t.Errorf("Expected dark buy to keep 8, got %d", o.Size)
+	}
+}
@@ -15,4 +15,88 @@ var (
 	ErrOrderDuplicate = errors.New("order hash already exists")
 	// ErrTimestampRequired returned when timestamp is not set (Web3 deterministic requirement)
 	ErrTimestampRequired = errors.New("timestamp is required for deterministic execution")
+	// ErrInsufficientFunds returned when the user cannot cover the order
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrInvalidAmount returned when a balance operation amount is invalid
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrReservedAccount returned when a trader uses the ID of a venue account
+	ErrReservedAccount = errors.New("account ID is reserved for the venue")
+	// ErrNoAccountManager returned when a balance operation needs an AccountManager and none is attached
+	ErrNoAccountManager = errors.New("no account manager")
+	// ErrNotionalOverflow returned when price * size does not fit the fixed-point range
+	ErrNotionalOverflow = errors.New("order notional overflows")
+	// ErrOrderSizeExceedsMax returned when order size is above the configured maximum
+	ErrOrderSizeExceedsMax = errors.New("order size exceeds maximum")
+	// ErrNotionalExceedsLimit returned when order notional is above the user's limit
+	ErrNotionalExceedsLimit = errors.New("order notional exceeds limit")
+	// ErrPriceOutOfBand returned when limit price deviates too far from the reference price
+	ErrPriceOutOfBand = errors.New("order price outside allowed band")
+	// ErrTooManyOpenOrders returned when the user has reached the open order cap
+	ErrTooManyOpenOrders = errors.New("too many open orders")
+	// ErrTooManyOpenOrdersAtLevel returned when the user has reached the open order cap at a price level
+	ErrTooManyOpenOrdersAtLevel = errors.New("too many open orders at price level")
+	// ErrPlaceRateLimited returned when the user exceeds the order placement rate
+	ErrPlaceRateLimited = errors.New("order placement rate limit exceeded")
+	// ErrCancelRateLimited returned when the user exceeds the order cancel rate
+	ErrCancelRateLimited = errors.New("order cancel rate limit exceeded")
+	// ErrSessionNotFound returned when the session does not exist
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExists returned when opening a session with an ID already in use
+	ErrSessionExists = errors.New("session already exists")
+	// ErrSessionDisconnected returned when placing an order on a disconnected session
+	ErrSessionDisconnected = errors.New("session disconnected")
+	// ErrSessionExpired returned when a heartbeat arrives after the session's grace period
+	ErrSessionExpired = errors.New("session expired")
+	// ErrSessionUserMismatch returned when an order is sent on a session of another user
+	ErrSessionUserMismatch = errors.New("session belongs to another user")
+	// ErrAuctionInProgress returned when starting an auction while one is running
+	ErrAuctionInProgress = errors.New("auction already in progress")
+	// ErrNoAuction returned when ending an auction that is not running
+	ErrNoAuction = errors.New("no auction in progress")
+	// ErrMarketOrderInAuction returned when a market order is sent during an auction
+	ErrMarketOrderInAuction = errors.New("market orders are not accepted during an auction")
+	// ErrPlaceNotAllowed returned when the trading state does not accept new orders
+	ErrPlaceNotAllowed = errors.New("order entry not allowed in current trading state")
+	// ErrCancelNotAllowed returned when the trading state does not accept cancels
+	ErrCancelNotAllowed = errors.New("cancel not allowed in current trading state")
+	// ErrInvalidTradingState returned for an unknown or no-op trading state transition
+	ErrInvalidTradingState = errors.New("invalid trading state transition")
+	// ErrOrderExpired returned when an order's expiry is not after its timestamp
+	ErrOrderExpired = errors.New("order already expired")
+	// ErrInvalidBlock returned when a block does not advance the height or goes back in time
+	ErrInvalidBlock = errors.New("block must advance height and not go back in time")
+	// ErrInvalidStopPrice returned when a stop order has no valid trigger price
+	ErrInvalidStopPrice = errors.New("invalid stop price")
+	// ErrInvalidTrail returned when a trailing stop does not set exactly one of TrailAmount and TrailBps
+	ErrInvalidTrail = errors.New("trailing stop requires either a trail amount or a trail percentage")
+	// ErrInvalidPeg returned when a pegged order is not a limit order
+	ErrInvalidPeg = errors.New("pegged orders must be limit orders")
+	// ErrNoPegReference returned when the reference price of a pegged order is not available
+	ErrNoPegReference = errors.New("peg reference price not available")
+	// ErrInvalidDarkOrder returned when an order cannot rest in the dark book
+	ErrInvalidDarkOrder = errors.New("invalid dark order")
+	// ErrMinQtyNotMet returned when an order's minimum quantity cannot be filled on entry
+	ErrMinQtyNotMet = errors.New("minimum quantity not available")
+	// ErrAllOrNoneCross returned when a limit order would rest crossing all-or-none orders it cannot fill
+	ErrAllOrNoneCross = errors.New("order would rest crossing all-or-none orders")
+	// ErrReduceOnly returned when a reduce-only order would not reduce the owner's position
+	ErrReduceOnly = errors.New("reduce-only order would increase position")
+	// ErrInsufficientMargin returned when the user's equity cannot cover the initial margin
+	ErrInsufficientMargin = errors.New("insufficient margin")
+	// ErrInvalidMarkPrice returned when a mark price update is not positive
+	ErrInvalidMarkPrice = errors.New("invalid mark price")
+	// ErrInvalidIndexPrice returned when an index price update is not positive
+	ErrInvalidIndexPrice = errors.New("invalid index price")
+	// ErrUnbalancedEntry returned when the postings of a journal entry do not net to zero
+	ErrUnbalancedEntry = errors.New("unbalanced journal entry")
+	// ErrLedgerImbalance returned when ledger balances of an asset do not sum to zero or differ from the accounts
+	ErrLedgerImbalance = errors.New("ledger balances do not reconcile")
+	// ErrInvalidQuoteOrder returned when a quote-sized order is not a market buy without base size
+	ErrInvalidQuoteOrder = errors.New("quote size is only supported on market buys without base size")
+	// ErrNoLiquidity returned when a quote-sized order finds no liquidity to size against
+	ErrNoLiquidity = errors.New("no liquidity")
+	// ErrInvalidLotSize returned when an order size is not a multiple of the lot size
+	ErrInvalidLotSize = errors.New("order size is not a multiple of the lot size")
+	// ErrInvalidOrderGroup returned when the orders of an OCO or bracket group are inconsistent
+	ErrInvalidOrderGroup = errors.New("invalid order group")
 )
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"container/heap"
+	"orderbook-matching-engine/orderbook"
+)
+
+// ExpiryClockMode selects which clock drives good-till-date/time expiry
+type ExpiryClockMode int
+
+const (
+	// ExpiryByEngineClock expires orders when ExpireDue is called, using the engine clock
+	ExpiryByEngineClock ExpiryClockMode = iota
+	// ExpiryByOrderTimestamp treats incoming order timestamps as block times: before each
+	// order is processed, every resting order expiring at or before its timestamp is removed.
+	// Expiry then depends only on the command stream (Web3 deterministic requirement).
+	ExpiryByOrderTimestamp
+)
+
+// WithExpiryClockMode selects the clock that drives expiry (ExpiryByEngineClock by default)
+func WithExpiryClockMode(mode ExpiryClockMode) Option {
+	return func(me *MatchingEngine) {
+		me.expiryMode = mode
+	}
+}
+
+// WithExecutionReportHandler registers a callback for execution reports generated by the engine
+// (e.g. expiries triggered implicitly by block time)
+func WithExecutionReportHandler(h func(orderbook.ExecutionReport)) Option {
+	return func(me *MatchingEngine) {
+		me.reportHandler = h
+	}
+}
+
+// expiryEntry is an element of the expiry index
+type expiryEntry struct {
+	at      int64
+	orderID uint64
+}
+
+// expiryIndex is a min-heap of resting and parked stop orders ordered by expiry time, then order ID.
+// Entries are removed lazily: orders that left the book are skipped when popped, and a stop that
+// triggered and rested under the same ID is reported once.
+type expiryIndex []expiryEntry
+
+func (h expiryIndex) Len() int { return len(h) }
+func (h expiryIndex) Less(i, j int) bool {
+	if h[i].at != h[j].at {
+		return h[i].at < h[j].at
+	}
+	return h[i].orderID < h[j].orderID
+}
+func (h expiryIndex) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *expiryIndex) Push(x any)   { *h = append(*h, x.(expiryEntry)) }
+func (h *expiryIndex) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// popDue removes the entries due at now and returns the IDs of the orders still resting
+// with the same expiry (key extracts the indexed expiry from an order)
+func (h *expiryIndex) popDue(lookup func(uint64) (*orderbook.Order, bool), now int64, key func(*orderbook.Order) int64) []uint64 {
+	var ids []uint64
+	for h.Len() > 0 && (*h)[0].at <= now {
+		e := heap.Pop(h).(expiryEntry)
+		// Skip stale entries for orders that already left the book
+		if len(ids) > 0 && ids[len(ids)-1] == e.orderID {
+			continue
+		}
+		if order, ok := lookup(e.orderID); ok && key(order) == e.at {
+			ids = append(ids, e.orderID)
+		}
+	}
+	return ids
+}
+
+func expireAt(o *orderbook.Order) int64    { return o.ExpireAt }
+func expireBlock(o *orderbook.Order) int64 { return int64(o.ExpireBlock) }
+
+// expirableOrder returns a resting lit or dark order or a parked stop
+func (me *MatchingEngine) expirableOrder(orderID uint64) (*orderbook.Order, bool) {
+	if order, ok := me.liveOrder(orderID); ok {
+		return order, true
+	}
+	return me.GetStopOrder(orderID)
+}
+
+// indexExpiry adds a resting or parked order to the expiry indexes
+func (me *MatchingEngine) indexExpiry(order *orderbook.Order) {
+	if order.ExpireAt > 0 {
+		heap.Push(&me.expiries, expiryEntry{at: order.ExpireAt, orderID: order.ID})
+	}
+	if order.ExpireBlock > 0 {
+		heap.Push(&me.blockExpiries, expiryEntry{at: int64(order.ExpireBlock), orderID: order.ID})
+	}
+}
+
+// ExpireDue expires orders against the engine clock
+func (me *MatchingEngine) ExpireDue() ([]orderbook.ExecutionReport, []orderbook.LevelUpdate) {
+	return me.ExpireOrders(me.clock.Now())
+}
+
+// ExpireOrders removes every resting order whose expiry is at or before now.
+// It returns an Expired execution report per order and the resulting L2 level updates,
+// which are also published on the market data feed.
+func (me *MatchingEngine) ExpireOrders(now int64) ([]orderbook.ExecutionReport, []orderbook.LevelUpdate) {
+	ids := me.expiries.popDue(me.expirableOrder, now, expireAt)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return me.expireOrders(ids, now)
+}
+
+// expireOrders removes the given orders and reports them as expired
+func (me *MatchingEngine) expireOrders(ids []uint64, now int64) ([]orderbook.ExecutionReport, []orderbook.LevelUpdate) {
+	result := me.cancelOrders(ids, now)
+	reports := make([]orderbook.ExecutionReport, 0, len(result.Cancelled))
+	for _, o := range result.Cancelled {
+		reports = append(reports, execReport(o, orderbook.ExecExpired, now))
+	}
+	me.emitReports(reports)
+	me.publishLevels(result.Levels, now)
+	return reports, result.Levels
+}
+
+// emitReports forwards execution reports to the registered handler
+func (me *MatchingEngine) emitReports(reports []orderbook.ExecutionReport) {
+	if me.reportHandler == nil {
+		return
+	}
+	for _, r := range reports {
+		me.reportHandler(r)
+	}
+}
+
+// checkExpiry applies block-time expiry before an order is processed and rejects orders already expired
+func (me *MatchingEngine) checkExpiry(order *orderbook.Order) error {
+	if me.expiryMode == ExpiryByOrderTimestamp {
+		me.ExpireOrders(order.Timestamp)
+	}
+	if order.ExpireAt > 0 && order.ExpireAt <= order.Timestamp {
+		return ErrOrderExpired
+	}
+	if order.ExpireBlock > 0 && order.ExpireBlock <= me.blockHeight {
+		return ErrOrderExpired
+	}
+	return nil
+}
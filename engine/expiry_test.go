@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestExpiry_EngineClock(t *testing.T) {
+	clock := NewManualClock(0)
+	feed := NewDefaultInMemoryMarketDataFeed()
+	var reports []orderbook.ExecutionReport
+	me := NewMatchingEngine(
+		WithClock(clock),
+		WithMarketDataPublisher(feed),
+		WithExecutionReportHandler(func(r orderbook.ExecutionReport) { reports = append(reports, r) }),
+	)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 99, Size: 10, Side: orderbook.Buy, Timestamp: 1, ExpireAt: 100})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 99, Size: 5, Side: orderbook.Buy, Timestamp: 1, ExpireAt: 200})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 101, Size: 7, Side: orderbook.Sell, Timestamp: 1, ExpireAt: 100})
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 98, Size: 3, Side: orderbook.Buy, Timestamp: 1})
+
+	clock.Set(99)
+	if r, _ := me.ExpireDue(); len(r) != 0 {
+		t.Fatalf("Nothing should expire yet, got %v", r)
+	}
+
+	clock.Set(100)
+	r, levels := me.ExpireDue()
+	if len(r) != 2 || r[0].OrderID != 1 || r[1].OrderID != 3 || r[0].Type != orderbook.ExecExpired {
+		t.Fatalf("Expected orders 1 and 3 expired, got %v", r)
+	}
+	if len(levels) != 2 || levels[0].Size != 5 || levels[1].Size != 0 {
+		t.Errorf("Unexpected level updates: %v", levels)
+	}
+	if len(reports) != 2 {
+		t.Errorf("Handler should receive 2 reports, got %d", len(reports))
+	}
+	if md := feed.Drain(); len(md) != 2 || md[0].Type != MDLevelUpdate || md[0].Level.Price != 99 {
+		t.Errorf("Unexpected market data: %v", md)
+	}
+	if _, ok := me.OrderBook.GetOrder(4); !ok {
+		t.Errorf("Order without expiry should remain")
+	}
+}
+
+func TestExpiry_BlockTimestamps(t *testing.T) {
+	me := NewMatchingEngine(WithExpiryClockMode(ExpiryByOrderTimestamp))
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 10, ExpireAt: 20})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 101, Size: 10, Side: orderbook.Sell, Timestamp: 10})
+
+	// Block at t=20 expires order 1 before the buy is matched
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 3, Price: 101, Size: 4, Side: orderbook.Buy, Timestamp: 20})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 1 || events[0].MakerOrderID != 2 {
+		t.Fatalf("Expired order must not trade, got %v", events)
+	}
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, Price: 100, Size: 1, Side: orderbook.Sell, Timestamp: 30, ExpireAt: 30}); !errors.Is(err, ErrOrderExpired) {
+		t.Errorf("Expected ErrOrderExpired, got %v", err)
+	}
+}
+
+func TestExpiry_ParkedStops(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Type: orderbook.Stop, StopPrice: 110, Size: 1, Side: orderbook.Buy, Timestamp: 1, ExpireAt: 10})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Type: orderbook.Stop, StopPrice: 90, Size: 1, Side: orderbook.Sell, Timestamp: 1, ExpireBlock: 2})
+	// A stop-limit that triggers and rests keeps its expiry
+	me.PlaceOrder(&orderbook.Order{ID: 3, Type: orderbook.StopLimit, StopPrice: 100, Price: 99, Size: 1, Side: orderbook.Buy, Timestamp: 1, ExpireAt: 10})
+	fill(me, 4, "alice", "bob", 100, 1)
+	if _, ok := me.OrderBook.GetOrder(3); !ok {
+		t.Fatalf("Triggered stop-limit should rest")
+	}
+
+	reports, _ := me.ExpireOrders(10)
+	if len(reports) != 2 || reports[0].OrderID != 1 || reports[1].OrderID != 3 || reports[0].Type != orderbook.ExecExpired {
+		t.Fatalf("Expected stop 1 and rested stop-limit 3 expired once each, got %+v", reports)
+	}
+	if _, ok := me.GetStopOrder(1); ok {
+		t.Fatalf("Expired stop should be removed")
+	}
+
+	res, _ := me.AdvanceBlock(2, 20)
+	if len(res.Expired) != 1 || res.Expired[0].OrderID != 2 {
+		t.Fatalf("Expected stop 2 expired at its block, got %+v", res.Expired)
+	}
+}
@@ -0,0 +1,233 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FeeRateScale is the fixed-point scale of fee rates (1_000_000 = 100%, 100 = 1bp)
+const FeeRateScale int64 = 1_000_000
+
+// DefaultFeeWindow is the rolling volume window used for tier assignment
+const DefaultFeeWindow = 30 * 24 * time.Hour
+
+// DefaultFeeAccount collects fees and pays rebates when the schedule names no fee account.
+// The fee account is reserved: orders and deposits under its ID are rejected.
+const DefaultFeeAccount = "venue"
+
+// feeBucket is the granularity of the rolling volume window
+const feeBucket = int64(24 * time.Hour)
+
+// FeeCurrency selects the asset fees are charged in
+type FeeCurrency int
+
+const (
+	// FeeInQuote charges both sides in the quote asset
+	FeeInQuote FeeCurrency = iota
+	// FeeInReceived charges each side in the asset it receives (buyer: base, seller: quote)
+	FeeInReceived
+)
+
+// FeeTier is a fee level unlocked by rolling traded volume
+type FeeTier struct {
+	MinVolume int64 `json:"min_volume"` // Rolling quote volume required, fixed-point
+	MakerRate int64 `json:"maker_rate"` // Negative values are rebates
+	TakerRate int64 `json:"taker_rate"`
+}
+
+// FeeSchedule configures maker/taker fees for an instrument
+type FeeSchedule struct {
+	Tiers      []FeeTier     `json:"tiers"`
+	Currency   FeeCurrency   `json:"currency"`
+	Window     time.Duration `json:"window"`      // Rolling volume window, DefaultFeeWindow if zero
+	FeeAccount string        `json:"fee_account"` // Venue account collecting fees and paying rebates, DefaultFeeAccount if empty
+}
+
+// FeeModel assigns fee tiers from rolling volume and computes per-fill fees.
+// Volume is bucketed by day of the match timestamp so tiers are deterministic on replay.
+type FeeModel struct {
+	mu       sync.RWMutex
+	schedule FeeSchedule
+	volumes  map[string]map[int64]int64 // UserID -> day -> quote volume
+}
+
+// NewFeeModel creates a fee model, sorting tiers by ascending volume threshold
+func NewFeeModel(schedule FeeSchedule) *FeeModel {
+	tiers := append([]FeeTier(nil), schedule.Tiers...)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinVolume < tiers[j].MinVolume })
+	schedule.Tiers = tiers
+	if schedule.Window <= 0 {
+		schedule.Window = DefaultFeeWindow
+	}
+	if schedule.FeeAccount == "" {
+		schedule.FeeAccount = DefaultFeeAccount
+	}
+	return &FeeModel{
+		schedule: schedule,
+		volumes:  make(map[string]map[int64]int64),
+	}
+}
+
+// WithFeeSchedule enables maker/taker fees on every fill
+func WithFeeSchedule(schedule FeeSchedule) Option {
+	return func(me *MatchingEngine) {
+		me.fees = NewFeeModel(schedule)
+	}
+}
+
+// Schedule returns the configured fee schedule
+func (fm *FeeModel) Schedule() FeeSchedule {
+	return fm.schedule
+}
+
+// Volume returns the user's traded quote volume within the window ending at now
+func (fm *FeeModel) Volume(userID string, now int64) int64 {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return fm.volume(userID, now)
+}
+
+func (fm *FeeModel) volume(userID string, now int64) int64 {
+	today := now / feeBucket
+	oldest := today - int64(fm.schedule.Window)/feeBucket + 1
+	total := int64(0)
+	for day, v := range fm.volumes[userID] {
+		if day >= oldest && day <= today {
+			total += v
+		}
+	}
+	return total
+}
+
+// Tier returns the tier the user currently qualifies for
+func (fm *FeeModel) Tier(userID string, now int64) FeeTier {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return fm.tier(userID, now)
+}
+
+func (fm *FeeModel) tier(userID string, now int64) FeeTier {
+	var tier FeeTier
+	vol := fm.volume(userID, now)
+	for _, t := range fm.schedule.Tiers {
+		if vol < t.MinVolume {
+			break
+		}
+		tier = t
+	}
+	return tier
+}
+
+// RecordVolume adds traded quote volume for the user and prunes expired buckets
+func (fm *FeeModel) RecordVolume(userID string, now, amount int64) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	days, ok := fm.volumes[userID]
+	if !ok {
+		days = make(map[int64]int64)
+		fm.volumes[userID] = days
+	}
+	today := now / feeBucket
+	days[today] += amount
+	oldest := today - int64(fm.schedule.Window)/feeBucket + 1
+	for day := range days {
+		if day < oldest {
+			delete(days, day)
+		}
+	}
+}
+
+// maxChargeRate returns the highest positive rate any tier may charge
+func (fm *FeeModel) maxChargeRate() int64 {
+	rate := int64(0)
+	for _, t := range fm.schedule.Tiers {
+		if t.MakerRate > rate {
+			rate = t.MakerRate
+		}
+		if t.TakerRate > rate {
+			rate = t.TakerRate
+		}
+	}
+	return rate
+}
+
+// ComputeFee returns amount * rate / FeeRateScale.
+// Charges are rounded up and rebates rounded toward zero, so rounding always favours the venue.
+func ComputeFee(amount, rate int64) int64 {
+	if rate >= 0 {
+		fee, _ := mulDivCeil(amount, rate, FeeRateScale)
+		return fee
+	}
+	rebate, _ := mulDiv(amount, -rate, FeeRateScale)
+	return -rebate
+}
+
+// orderFee returns the fee an order pays for one fill.
+// Positive fees are rounded up once per order rather than once per fill: the fraction
+// overpaid on a fill is carried and credited against the next one, so the total charged
+// to an order never exceeds its exact cumulative fee rounded up.
+func (me *MatchingEngine) orderFee(orderID uint64, amount, rate int64) int64 {
+	if rate <= 0 {
+		return ComputeFee(amount, rate)
+	}
+	quo, rem, ok := mulDivRem(amount, rate, FeeRateScale)
+	if !ok {
+		return ComputeFee(amount, rate)
+	}
+	prepaid := me.feeCarry[orderID]
+	var fee int64
+	if rem > prepaid {
+		fee = quo + 1
+		prepaid = FeeRateScale - (rem - prepaid)
+	} else {
+		fee = quo
+		prepaid -= rem
+	}
+	if prepaid == 0 {
+		delete(me.feeCarry, orderID)
+	} else {
+		me.feeCarry[orderID] = prepaid
+	}
+	return fee
+}
+
+// buyerPaysFeeFromReserve reports whether buy orders must reserve fees on top of notional
+func (me *MatchingEngine) buyerPaysFeeFromReserve() bool {
+	return me.fees != nil && me.fees.schedule.Currency == FeeInQuote
+}
+
+// feeFor returns the fee and fee asset for one side of a fill
+func (me *MatchingEngine) feeFor(order *orderbook.Order, rate, size, quote int64) (int64, string) {
+	if me.fees.schedule.Currency == FeeInReceived && order.Side == orderbook.Buy {
+		return me.orderFee(order.ID, size, rate), me.instrument.BaseAsset
+	}
+	return me.orderFee(order.ID, quote, rate), me.instrument.QuoteAsset
+}
+
+// applyFees fills in the fee fields of a match event and records traded volume.
+// Tiers are resolved from volume before this fill.
+func (me *MatchingEngine) applyFees(ev *orderbook.MatchEvent, maker, taker *orderbook.Order) {
+	if me.fees == nil {
+		return
+	}
+	quote, _ := me.notional(ev.Price, ev.Size)
+	makerTier := me.fees.Tier(maker.UserID, ev.Timestamp)
+	takerTier := me.fees.Tier(taker.UserID, ev.Timestamp)
+	ev.MakerFee, ev.MakerFeeAsset = me.feeFor(maker, makerTier.MakerRate, ev.Size, quote)
+	ev.TakerFee, ev.TakerFeeAsset = me.feeFor(taker, takerTier.TakerRate, ev.Size, quote)
+
+	me.fees.RecordVolume(maker.UserID, ev.Timestamp, quote)
+	me.fees.RecordVolume(taker.UserID, ev.Timestamp, quote)
+}
+
+// reservedAccount reports whether an ID names a venue account (the fee account or the ledger's
+// ExternalAccount), which traders may not place orders or deposit funds as
+func (me *MatchingEngine) reservedAccount(userID string) bool {
+	feeAccount := DefaultFeeAccount
+	if me.fees != nil {
+		feeAccount = me.fees.schedule.FeeAccount
+	}
+	return userID == feeAccount || userID == ExternalAccount
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+	"time"
+)
+
+func newFeeEngine(t *testing.T, currency FeeCurrency) (*MatchingEngine, AccountManager) {
+	t.Helper()
+	am := NewDefaultInMemoryAccountManager()
+	me := NewMatchingEngine(
+		WithInstrument(Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", QuantityScale: 1}),
+		WithAccountManager(am),
+		WithFeeSchedule(FeeSchedule{
+			Tiers: []FeeTier{
+				{MinVolume: 5000, MakerRate: -100, TakerRate: 500}, // -1bp / 5bp
+				{MinVolume: 0, MakerRate: 1000, TakerRate: 2000},   // 10bp / 20bp
+			},
+			Currency:   currency,
+			FeeAccount: "venue",
+		}),
+	)
+	am.Deposit("alice", "BTC", 1000)
+	am.Deposit("bob", "USD", 1000000)
+	return me, am
+}
+
+func TestFees_QuoteCurrency(t *testing.T) {
+	me, am := newFeeEngine(t, FeeInQuote)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 1000, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "bob", Price: 1000, Size: 10, Side: orderbook.Buy, Timestamp: 2})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	ev := events[0]
+	// Notional 10000: maker 10bp = 10, taker 20bp = 20
+	if ev.MakerFee != 10 || ev.TakerFee != 20 || ev.MakerFeeAsset != "USD" || ev.TakerFeeAsset != "USD" {
+		t.Errorf("Unexpected fees: %+v", ev)
+	}
+	if b := am.Balance("alice", "USD"); b.Available != 9990 {
+		t.Errorf("Alice USD: %+v", b)
+	}
+	if b := am.Balance("bob", "USD"); b.Available != 1000000-10020 || b.Reserved != 0 {
+		t.Errorf("Bob USD: %+v", b)
+	}
+	if b := am.Balance("venue", "USD"); b.Available != 30 {
+		t.Errorf("Venue USD: %+v", b)
+	}
+
+	// Both users now have 10000 volume and qualify for the rebate tier
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 1000, Size: 10, Side: orderbook.Sell, Timestamp: 3})
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "bob", Price: 1000, Size: 10, Side: orderbook.Buy, Timestamp: 4})
+	if events[0].MakerFee != -1 || events[0].TakerFee != 5 {
+		t.Errorf("Unexpected tiered fees: %+v", events[0])
+	}
+	if b := am.Balance("venue", "USD"); b.Available != 34 {
+		t.Errorf("Venue USD after rebate: %+v", b)
+	}
+}
+
+func TestFees_ReceivedCurrency(t *testing.T) {
+	me, am := newFeeEngine(t, FeeInReceived)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "bob", Price: 1000, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "alice", Price: 1000, Size: 10, Side: orderbook.Sell, Timestamp: 2})
+	ev := events[0]
+	// Maker buyer pays 10bp of 10 BTC = 0.01 rounded up to 1, taker seller pays 20bp of 10000 USD = 20
+	if ev.MakerFee != 1 || ev.MakerFeeAsset != "BTC" || ev.TakerFee != 20 || ev.TakerFeeAsset != "USD" {
+		t.Errorf("Unexpected fees: %+v", ev)
+	}
+	if b := am.Balance("bob", "BTC"); b.Available != 9 {
+		t.Errorf("Bob BTC: %+v", b)
+	}
+	if b := am.Balance("alice", "USD"); b.Available != 9980 {
+		t.Errorf("Alice USD: %+v", b)
+	}
+}
+
+func TestFees_RoundingCarriedAcrossFills(t *testing.T) {
+	me, _ := newFeeEngine(t, FeeInQuote)
+
+	// Ten fills of notional 100 at 20bp are 0.2 each: charged 1 on the first fill, then carried
+	for i := uint64(1); i <= 10; i++ {
+		me.PlaceOrder(&orderbook.Order{ID: i, UserID: "alice", Price: 100, Size: 1, Side: orderbook.Sell, Timestamp: 1})
+	}
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 100, UserID: "bob", Price: 100, Size: 10, Side: orderbook.Buy, Timestamp: 2})
+	total := int64(0)
+	for _, e := range events {
+		total += e.TakerFee
+	}
+	if total != 2 {
+		t.Errorf("Expected cumulative taker fee 2, got %d", total)
+	}
+}
+
+func TestFees_RollingVolumeWindow(t *testing.T) {
+	fm := NewFeeModel(FeeSchedule{Tiers: []FeeTier{{MinVolume: 0, TakerRate: 10}, {MinVolume: 100, TakerRate: 5}}})
+	day := int64(24 * time.Hour)
+
+	fm.RecordVolume("u", 0, 100)
+	if fm.Tier("u", 29*day).TakerRate != 5 {
+		t.Errorf("Volume should count within 30 days")
+	}
+	if fm.Tier("u", 30*day).TakerRate != 10 {
+		t.Errorf("Volume should expire after 30 days")
+	}
+}
+
+func TestFees_DefaultFeeAccount(t *testing.T) {
+	am := NewDefaultInMemoryAccountManager()
+	me := NewMatchingEngine(
+		WithInstrument(Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", QuantityScale: 1}),
+		WithAccountManager(am),
+		WithFeeSchedule(FeeSchedule{Tiers: []FeeTier{{MakerRate: 1000, TakerRate: 2000}}}),
+	)
+	am.Deposit("alice", "BTC", 10)
+	am.Deposit("bob", "USD", 20000)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 1000, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "bob", Price: 1000, Size: 10, Side: orderbook.Buy, Timestamp: 2})
+	if b := am.Balance(DefaultFeeAccount, "USD"); b.Available != 30 {
+		t.Errorf("Fees should go to the default fee account, got %+v", b)
+	}
+	if b := am.Balance("", "USD"); b.Total() != 0 {
+		t.Errorf("No fees may reach an unnamed account, got %+v", b)
+	}
+}
+
+func TestFees_FeeAccountReserved(t *testing.T) {
+	me, _ := newFeeEngine(t, FeeInQuote)
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "venue", Price: 1000, Size: 1, Side: orderbook.Sell}); !errors.Is(err, ErrReservedAccount) {
+		t.Fatalf("Expected ErrReservedAccount for an order, got %v", err)
+	}
+	if err := me.Deposit("venue", "USD", 100); !errors.Is(err, ErrReservedAccount) {
+		t.Fatalf("Expected ErrReservedAccount for a deposit, got %v", err)
+	}
+	if err := me.DepositCollateral(ExternalAccount, 100); !errors.Is(err, ErrReservedAccount) {
+		t.Errorf("Expected ErrReservedAccount for collateral, got %v", err)
+	}
+}
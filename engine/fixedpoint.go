@@ -0,0 +1,65 @@
+package engine
+
+import "math/bits"
+
+// mulDiv returns floor(a*b/c) for non-negative operands using a 128-bit intermediate.
+// ok is false if the result does not fit in an int64 or an operand is invalid.
+func mulDiv(a, b, c int64) (int64, bool) {
+	if a < 0 || b < 0 || c <= 0 {
+		return 0, false
+	}
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi >= uint64(c) {
+		// Quotient would overflow 64 bits (bits.Div64 panics in this case)
+		return 0, false
+	}
+	quo, _ := bits.Div64(hi, lo, uint64(c))
+	if quo > 1<<63-1 {
+		return 0, false
+	}
+	return int64(quo), true
+}
+
+// mulDivCeil returns ceil(a*b/c) for non-negative operands using a 128-bit intermediate.
+func mulDivCeil(a, b, c int64) (int64, bool) {
+	if a < 0 || b < 0 || c <= 0 {
+		return 0, false
+	}
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi >= uint64(c) {
+		return 0, false
+	}
+	quo, rem := bits.Div64(hi, lo, uint64(c))
+	if rem != 0 {
+		quo++
+	}
+	if quo > 1<<63-1 {
+		return 0, false
+	}
+	return int64(quo), true
+}
+
+// mulDivRem returns the quotient and remainder of a*b/c for non-negative operands
+func mulDivRem(a, b, c int64) (int64, int64, bool) {
+	if a < 0 || b < 0 || c <= 0 {
+		return 0, 0, false
+	}
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi >= uint64(c) {
+		return 0, 0, false
+	}
+	quo, rem := bits.Div64(hi, lo, uint64(c))
+	if quo > 1<<63-1 {
+		return 0, 0, false
+	}
+	return int64(quo), int64(rem), true
+}
+
+// mulDivSigned returns a*b/c rounded towards zero for a signed a and non-negative b and c
+func mulDivSigned(a, b, c int64) (int64, bool) {
+	q, ok := mulDiv(abs(a), b, c)
+	if a < 0 {
+		q = -q
+	}
+	return q, ok
+}
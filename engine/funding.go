@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sort"
+)
+
+// FundingRateScale is the fixed-point scale of funding rates (1_000_000 = 100% per interval)
+const FundingRateScale int64 = 1_000_000
+
+// FundingConfig configures perpetual funding. Every index price update samples the premium of
+// the book's impact prices over the index; at each interval boundary the average premium plus
+// the interest rate, capped, is the funding rate. Positive rates move funds from longs to shorts.
+// Positions pushed under the maintenance margin by a funding debit are liquidated.
+type FundingConfig struct {
+	// Interval between settlements in nanoseconds; boundaries are multiples of Interval
+	Interval int64
+	// Start is the first boundary to settle, rounded up to a multiple of Interval. 0 starts at the
+	// first boundary after the first index price update or funding run, whichever comes first.
+	Start int64
+	// InterestRate is added to the premium each interval (FundingRateScale)
+	InterestRate int64
+	// MaxRate caps the absolute funding rate (FundingRateScale), 0 = uncapped
+	MaxRate int64
+}
+
+// WithFunding enables periodic funding settlement
+func WithFunding(cfg FundingConfig) Option {
+	return func(me *MatchingEngine) {
+		me.funding = &cfg
+		if cfg.Start > 0 && cfg.Interval > 0 {
+			me.nextFunding = (cfg.Start + cfg.Interval - 1) / cfg.Interval * cfg.Interval
+		}
+	}
+}
+
+// FundingPayment is the funding paid or received by a user
+type FundingPayment struct {
+	UserID string `json:"user_id"`
+	Size   int64  `json:"size"`   // Signed position funded
+	Amount int64  `json:"amount"` // Collateral received, negative when paid
+}
+
+// FundingEvent describes one funding settlement
+type FundingEvent struct {
+	Timestamp int64            `json:"timestamp"` // Interval boundary
+	Rate      int64            `json:"rate"`      // FundingRateScale
+	Premium   int64            `json:"premium"`   // Average premium index over the interval
+	MarkPrice int64            `json:"mark_price"`
+	Payments  []FundingPayment `json:"payments,omitempty"`
+	Dust      int64            `json:"dust"` // Rounding remainder credited to the insurance fund
+	// Liquidations of positions the settlement left under-margined
+	Liquidations []Liquidation `json:"liquidations,omitempty"`
+}
+
+// PredictedFundingRate returns the rate the next settlement would apply with the samples so far
+func (me *MatchingEngine) PredictedFundingRate() int64 {
+	if me.funding == nil {
+		return 0
+	}
+	rate, _ := me.fundingRate()
+	return rate
+}
+
+// ProcessFunding settles every funding interval that ended at or before the engine clock.
+// Returns the settlements and the fills of the liquidations they triggered.
+func (me *MatchingEngine) ProcessFunding() ([]FundingEvent, []orderbook.MatchEvent) {
+	return me.processFunding(me.clock.Now())
+}
+
+// startFunding schedules the first boundary after now unless one is already set
+func (me *MatchingEngine) startFunding(now int64) {
+	if me.nextFunding == 0 && me.funding.Interval > 0 {
+		me.nextFunding = (now/me.funding.Interval + 1) * me.funding.Interval
+	}
+}
+
+// samplePremium records the premium of the impact prices over the index price.
+// Samples with a side too thin for the impact notional are skipped.
+func (me *MatchingEngine) samplePremium(bid, ask, now int64) {
+	if me.funding == nil {
+		return
+	}
+	me.startFunding(now)
+	if bid <= 0 || ask <= 0 {
+		return
+	}
+	index := me.indexPrice
+	premium := int64(0)
+	if bid > index {
+		premium += bid - index
+	}
+	if ask < index {
+		premium -= index - ask
+	}
+	p, _ := mulDivSigned(premium, FundingRateScale, index)
+	me.premiumSum += p
+	me.premiumSamples++
+}
+
+// fundingRate returns the capped rate and the average premium of the current interval
+func (me *MatchingEngine) fundingRate() (rate, premium int64) {
+	if me.premiumSamples > 0 {
+		premium = me.premiumSum / me.premiumSamples
+	}
+	rate = premium + me.funding.InterestRate
+	if limit := me.funding.MaxRate; limit > 0 {
+		rate = min(max(rate, -limit), limit)
+	}
+	return rate, premium
+}
+
+// processFunding settles the interval boundaries up to now, in order, liquidating after each
+// settlement that moved collateral
+func (me *MatchingEngine) processFunding(now int64) ([]FundingEvent, []orderbook.MatchEvent) {
+	if me.funding == nil || me.funding.Interval <= 0 {
+		return nil, nil
+	}
+	if me.nextFunding == 0 {
+		me.startFunding(now)
+		return nil, nil
+	}
+	var events []FundingEvent
+	var fills []orderbook.MatchEvent
+	for me.nextFunding <= now {
+		ev := me.settleFunding(me.nextFunding)
+		if len(ev.Payments) > 0 {
+			liqs, evs := me.liquidate(now)
+			ev.Liquidations = liqs
+			fills = append(fills, evs...)
+		}
+		me.publish(MarketDataEvent{Type: MDFunding, Timestamp: ev.Timestamp, Funding: &ev})
+		events = append(events, ev)
+		me.nextFunding += me.funding.Interval
+	}
+	return events, fills
+}
+
+// settleFunding pays funding on every open position at the mark price. Payers are charged the
+// rounded-down amount of their own notional; receivers share the total pro rata to their size.
+// Users are processed in sorted order so settlement is deterministic.
+func (me *MatchingEngine) settleFunding(at int64) FundingEvent {
+	rate, premium := me.fundingRate()
+	me.premiumSum, me.premiumSamples = 0, 0
+
+	price := me.markPrice
+	if price == 0 {
+		price = me.indexPrice
+	}
+	ev := FundingEvent{Timestamp: at, Rate: rate, Premium: premium, MarkPrice: price}
+	if rate == 0 || price == 0 {
+		return ev
+	}
+
+	users := make([]string, 0, len(me.positions))
+	for u, p := range me.positions {
+		if p.Size != 0 {
+			users = append(users, u)
+		}
+	}
+	sort.Strings(users)
+
+	// Longs pay a positive rate, shorts a negative one
+	payerLong := rate > 0
+	total, receiverSize := int64(0), int64(0)
+	for _, u := range users {
+		size := me.positions[u].Size
+		if (size > 0) != payerLong {
+			receiverSize += abs(size)
+			continue
+		}
+		notional, _ := me.notional(price, abs(size))
+		amount, _ := mulDiv(notional, abs(rate), FundingRateScale)
+		ev.Payments = append(ev.Payments, FundingPayment{UserID: u, Size: size, Amount: -amount})
+		total += amount
+	}
+	if receiverSize == 0 {
+		// No counterparty to pay: nothing is charged
+		ev.Payments = nil
+		return ev
+	}
+
+	paid := int64(0)
+	for _, u := range users {
+		size := me.positions[u].Size
+		if (size > 0) == payerLong {
+			continue
+		}
+		amount, _ := mulDiv(total, abs(size), receiverSize)
+		ev.Payments = append(ev.Payments, FundingPayment{UserID: u, Size: size, Amount: amount})
+		paid += amount
+	}
+	sort.Slice(ev.Payments, func(i, j int) bool { return ev.Payments[i].UserID < ev.Payments[j].UserID })
+	for _, p := range ev.Payments {
+		me.collateral[p.UserID] += p.Amount
+	}
+	ev.Dust = total - paid
+	me.insuranceFund += ev.Dust
+	return ev
+}
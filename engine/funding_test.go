@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestFunding_SettlesAtIntervalBoundaries(t *testing.T) {
+	feed := NewDefaultInMemoryMarketDataFeed()
+	me := NewMatchingEngine(
+		WithClock(NewManualClock(0)),
+		WithMarkPrice(MarkPriceConfig{}),
+		WithFunding(FundingConfig{Interval: 100, MaxRate: 5000}),
+		WithMarketDataPublisher(feed),
+	)
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "bob", Price: 100, Size: 7 * unit, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "dan", Price: 100, Size: 3 * unit, Side: orderbook.Sell, Timestamp: 2})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 100, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "carol", Price: 102, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 4})
+	me.PlaceOrder(&orderbook.Order{ID: 5, UserID: "carol", Price: 104, Size: 10 * unit, Side: orderbook.Sell, Timestamp: 5})
+
+	// Premium 2% capped at 0.5%, mark 103
+	me.UpdateIndexPrice(100)
+	if rate := me.PredictedFundingRate(); rate != 5000 {
+		t.Fatalf("Expected predicted rate 5000, got %d", rate)
+	}
+
+	if res, _ := me.AdvanceBlock(1, 50); len(res.Funding) != 0 {
+		t.Fatalf("No boundary passed yet, got %+v", res.Funding)
+	}
+	feed.Drain()
+	res, _ := me.AdvanceBlock(2, 250)
+	if len(res.Funding) != 2 || res.Funding[0].Timestamp != 100 || res.Funding[1].Timestamp != 200 {
+		t.Fatalf("Expected settlements at 100 and 200, got %+v", res.Funding)
+	}
+
+	// Alice pays 0.5% of 1030; shorts share it 7:3, the rounding remainder goes to the fund
+	ev := res.Funding[0]
+	want := []FundingPayment{
+		{UserID: "alice", Size: 10 * unit, Amount: -5},
+		{UserID: "bob", Size: -7 * unit, Amount: 3},
+		{UserID: "dan", Size: -3 * unit, Amount: 1},
+	}
+	if ev.Rate != 5000 || ev.MarkPrice != 103 || len(ev.Payments) != len(want) || ev.Dust != 1 {
+		t.Fatalf("Unexpected funding event %+v", ev)
+	}
+	for i, p := range want {
+		if ev.Payments[i] != p {
+			t.Errorf("Payment %d: expected %+v, got %+v", i, p, ev.Payments[i])
+		}
+	}
+	if me.MarginAccount("alice").Collateral != -5 || me.MarginAccount("bob").Collateral != 3 || me.InsuranceFund() != 1 {
+		t.Errorf("Funding not applied to collateral")
+	}
+
+	// No samples in the second interval
+	if res.Funding[1].Rate != 0 || len(res.Funding[1].Payments) != 0 {
+		t.Errorf("Expected an empty second settlement, got %+v", res.Funding[1])
+	}
+	if md := feed.Drain(); len(md) != 2 || md[0].Type != MDFunding {
+		t.Errorf("Expected funding events on the feed, got %v", md)
+	}
+}
+
+func TestFunding_ThinSideSkipped(t *testing.T) {
+	me := NewMatchingEngine(WithClock(NewManualClock(0)), WithFunding(FundingConfig{Interval: 100}))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 102, Size: unit, Side: orderbook.Buy, Timestamp: 1})
+
+	// Without an ask the premium is unknown, not zero
+	me.UpdateIndexPrice(100)
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 104, Size: unit, Side: orderbook.Sell, Timestamp: 2})
+	me.UpdateIndexPrice(100)
+	if rate := me.PredictedFundingRate(); rate != 20000 {
+		t.Fatalf("Expected the one valid 2%% sample, got %d", rate)
+	}
+}
+
+func TestFunding_StartSettlesEarlierBoundaries(t *testing.T) {
+	me := NewMatchingEngine(WithFunding(FundingConfig{Interval: 100, Start: 50, InterestRate: 100}))
+	fill(me, 1, "alice", "bob", 100, 10*unit)
+
+	// The first run already settles the boundaries since Start
+	res, _ := me.AdvanceBlock(1, 250)
+	if len(res.Funding) != 2 || res.Funding[0].Timestamp != 100 || res.Funding[1].Timestamp != 200 {
+		t.Fatalf("Expected settlements at 100 and 200, got %+v", res.Funding)
+	}
+}
+
+func TestFunding_DebitLiquidates(t *testing.T) {
+	me := NewMatchingEngine(
+		WithClock(NewManualClock(0)),
+		WithMargin(MarginConfig{InitialBps: 1000, MaintenanceBps: 500}),
+		WithFunding(FundingConfig{Interval: 100, InterestRate: 80000}),
+	)
+	me.DepositCollateral("alice", 60)
+	me.DepositCollateral("bob", 1000)
+	me.DepositCollateral("carol", 1000)
+	me.SetMarkPrice(100)
+	fill(me, 1, "alice", "bob", 100, 5*unit)
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "carol", Price: 99, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 3})
+	me.ProcessFunding()
+
+	// Alice pays 8% of 500: equity 20 against maintenance 25
+	res, fills := me.processFunding(100)
+	if len(res) != 1 || len(res[0].Liquidations) != 1 || res[0].Liquidations[0].UserID != "alice" {
+		t.Fatalf("Expected alice liquidated after the funding debit, got %+v", res)
+	}
+	if len(fills) != 1 || fills[0].MakerOrderID != 3 || me.Position("alice").Size != 0 {
+		t.Fatalf("Expected the position closed against order 3, got %v", fills)
+	}
+}
+
+func TestFunding_NegativeRate(t *testing.T) {
+	me := NewMatchingEngine(WithClock(NewManualClock(0)), WithFunding(FundingConfig{Interval: 100, MaxRate: 5000}))
+	fill(me, 1, "alice", "bob", 100, 10*unit)
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "carol", Price: 96, Size: unit, Side: orderbook.Buy, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "carol", Price: 98, Size: unit, Side: orderbook.Sell, Timestamp: 4})
+
+	// The book trades 2% below the index: capped at -0.5%, shorts pay longs
+	me.UpdateIndexPrice(100)
+	if rate := me.PredictedFundingRate(); rate != -5000 {
+		t.Fatalf("Expected predicted rate -5000, got %d", rate)
+	}
+	res, _ := me.processFunding(100)
+	if len(res) != 1 || res[0].Rate != -5000 {
+		t.Fatalf("Expected a settlement at -5000, got %+v", res)
+	}
+	// 0.5% of 970 at the mark of 97
+	if me.MarginAccount("bob").Collateral != -4 || me.MarginAccount("alice").Collateral != 4 {
+		t.Errorf("Expected bob to pay alice 4, got %+v", res[0].Payments)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import "math"
+
+// Instrument describes the traded pair and its fixed-point conventions
+type Instrument struct {
+	Symbol     string `json:"symbol"`
+	BaseAsset  string `json:"base_asset"`
+	QuoteAsset string `json:"quote_asset"`
+	// QuantityScale is the fixed-point scale of Order.Size (e.g. 1e8).
+	// Quote notional is computed as Price * Size / QuantityScale.
+	QuantityScale int64 `json:"quantity_scale"`
+	// LotSize is the size increment orders must be placed in (0 or 1 = any size).
+	// Sizes derived from quote amounts are rounded down to it.
+	LotSize int64 `json:"lot_size,omitempty"`
+}
+
+// DefaultInstrument returns the instrument used when none is configured
+func DefaultInstrument() Instrument {
+	return Instrument{
+		Symbol:        "BASE-QUOTE",
+		BaseAsset:     "BASE",
+		QuoteAsset:    "QUOTE",
+		QuantityScale: 1e8,
+	}
+}
+
+// WithInstrument configures the instrument traded by the engine
+func WithInstrument(inst Instrument) Option {
+	return func(me *MatchingEngine) {
+		if inst.QuantityScale <= 0 {
+			inst.QuantityScale = DefaultInstrument().QuantityScale
+		}
+		me.instrument = inst
+	}
+}
+
+// Instrument returns the instrument traded by the engine
+func (me *MatchingEngine) Instrument() Instrument {
+	return me.instrument
+}
+
+// notional returns the quote amount of size at price, rounded down
+func (me *MatchingEngine) notional(price, size int64) (int64, bool) {
+	return mulDiv(price, size, me.instrument.QuantityScale)
+}
+
+// notionalCeil returns the quote amount of size at price, rounded up
+func (me *MatchingEngine) notionalCeil(price, size int64) (int64, bool) {
+	return mulDivCeil(price, size, me.instrument.QuantityScale)
+}
+
+// roundLot rounds a size down to the lot size
+func (me *MatchingEngine) roundLot(size int64) int64 {
+	if lot := me.instrument.LotSize; lot > 1 {
+		return size - size%lot
+	}
+	return size
+}
+
+// sizeForQuote returns the largest lot-rounded size whose notional at price does not exceed quote
+func (me *MatchingEngine) sizeForQuote(quote, price int64) int64 {
+	size, ok := mulDiv(quote, me.instrument.QuantityScale, price)
+	if !ok {
+		size = math.MaxInt64
+	}
+	return me.roundLot(size)
+}
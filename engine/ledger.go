@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sync"
+)
+
+// ExternalAccount is the ledger counterparty of deposits and withdrawals
+const ExternalAccount = "external"
+
+// Posting is one leg of a journal entry. Amount is signed: positive credits the account's
+// holding of the asset, negative debits it.
+type Posting struct {
+	Account string `json:"account"`
+	Asset   string `json:"asset"`
+	Amount  int64  `json:"amount"`
+}
+
+// JournalEntry holds the postings of a single fill or transfer; they net to zero per asset
+type JournalEntry struct {
+	Seq          uint64    `json:"seq"`
+	Timestamp    int64     `json:"timestamp"`
+	MakerOrderID uint64    `json:"maker_order_id"`
+	TakerOrderID uint64    `json:"taker_order_id"`
+	Postings     []Posting `json:"postings"`
+}
+
+// balanced reports whether the postings of an entry net to zero in every asset
+func (e *JournalEntry) balanced() bool {
+	net := make(map[string]int64, 2)
+	for _, p := range e.Postings {
+		net[p.Asset] += p.Amount
+	}
+	for _, v := range net {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Ledger defines the interface for the double-entry store fed with every fill
+type Ledger interface {
+	// Post appends an entry, rejecting it if its postings do not balance
+	Post(entry JournalEntry) error
+	// Entries returns the entries touching an account with from <= Timestamp <= to, in posting order
+	Entries(account string, from, to int64) []JournalEntry
+	// Balance returns the net of all postings to an account in an asset
+	Balance(account, asset string) int64
+	// Reconcile checks that the balances of every asset sum to zero across accounts and that
+	// every account other than ExternalAccount holds in the ledger what it holds in accounts
+	Reconcile(accounts AccountManager) error
+}
+
+// inMemoryLedger is a thread-safe in-memory Ledger
+type inMemoryLedger struct {
+	mu        sync.RWMutex
+	entries   []JournalEntry
+	byAccount map[string][]int            // Account -> indexes into entries
+	balances  map[string]map[string]int64 // Account -> Asset -> balance
+}
+
+// NewInMemoryLedger provides a thread-safe in-memory implementation
+func NewInMemoryLedger() Ledger {
+	return &inMemoryLedger{
+		byAccount: make(map[string][]int),
+		balances:  make(map[string]map[string]int64),
+	}
+}
+
+func (l *inMemoryLedger) Post(entry JournalEntry) error {
+	if !entry.balanced() {
+		return ErrUnbalancedEntry
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	idx := len(l.entries)
+	l.entries = append(l.entries, entry)
+	for _, p := range entry.Postings {
+		list := l.byAccount[p.Account]
+		if len(list) == 0 || list[len(list)-1] != idx {
+			l.byAccount[p.Account] = append(list, idx)
+		}
+		assets, ok := l.balances[p.Account]
+		if !ok {
+			assets = make(map[string]int64)
+			l.balances[p.Account] = assets
+		}
+		assets[p.Asset] += p.Amount
+	}
+	return nil
+}
+
+func (l *inMemoryLedger) Entries(account string, from, to int64) []JournalEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var out []JournalEntry
+	for _, i := range l.byAccount[account] {
+		if e := l.entries[i]; e.Timestamp >= from && e.Timestamp <= to {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (l *inMemoryLedger) Balance(account, asset string) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.balances[account][asset]
+}
+
+func (l *inMemoryLedger) Reconcile(accounts AccountManager) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	totals := make(map[string]int64)
+	for account, assets := range l.balances {
+		for asset, v := range assets {
+			totals[asset] += v
+			if account != ExternalAccount && accounts.Balance(account, asset).Total() != v {
+				return ErrLedgerImbalance
+			}
+		}
+	}
+	for _, v := range totals {
+		if v != 0 {
+			return ErrLedgerImbalance
+		}
+	}
+	return nil
+}
+
+// WithLedger records double-entry postings for every fill
+func WithLedger(l Ledger) Option {
+	return func(me *MatchingEngine) {
+		me.ledger = l
+	}
+}
+
+// Ledger returns the attached ledger, or nil
+func (me *MatchingEngine) Ledger() Ledger {
+	return me.ledger
+}
+
+// Deposit credits funds to a user through the AccountManager and records the transfer in the
+// ledger. Funds must enter through the engine for the ledger to reconcile with the accounts.
+func (me *MatchingEngine) Deposit(userID, asset string, amount int64) error {
+	if me.accounts == nil {
+		return ErrNoAccountManager
+	}
+	if me.reservedAccount(userID) {
+		return ErrReservedAccount
+	}
+	if err := me.accounts.Deposit(userID, asset, amount); err != nil {
+		return err
+	}
+	me.postTransfer(userID, asset, amount)
+	return nil
+}
+
+// Withdraw debits funds from a user through the AccountManager and records the transfer in the ledger
+func (me *MatchingEngine) Withdraw(userID, asset string, amount int64) error {
+	if me.accounts == nil {
+		return ErrNoAccountManager
+	}
+	if err := me.accounts.Withdraw(userID, asset, amount); err != nil {
+		return err
+	}
+	me.postTransfer(userID, asset, -amount)
+	return nil
+}
+
+// postTransfer records funds moving between a user and ExternalAccount
+func (me *MatchingEngine) postTransfer(userID, asset string, amount int64) {
+	if me.ledger == nil {
+		return
+	}
+	me.ledgerSeq++
+	me.ledger.Post(JournalEntry{
+		Seq:       me.ledgerSeq,
+		Timestamp: me.clock.Now(),
+		Postings: []Posting{
+			{Account: userID, Asset: asset, Amount: amount},
+			{Account: ExternalAccount, Asset: asset, Amount: -amount},
+		},
+	})
+}
+
+// postFill records the base and quote legs of a settlement and its fees or rebates.
+// Fees move from the paying user to the fee account, rebates the other way.
+func (me *MatchingEngine) postFill(ev *orderbook.MatchEvent, s Settlement) {
+	if me.ledger == nil {
+		return
+	}
+	venue := s.FeeAccount
+	me.ledgerSeq++
+	entry := JournalEntry{
+		Seq:          me.ledgerSeq,
+		Timestamp:    ev.Timestamp,
+		MakerOrderID: ev.MakerOrderID,
+		TakerOrderID: ev.TakerOrderID,
+		Postings: []Posting{
+			{Account: s.Buyer, Asset: s.BaseAsset, Amount: s.BaseAmount},
+			{Account: s.Seller, Asset: s.BaseAsset, Amount: -s.BaseAmount},
+			{Account: s.Seller, Asset: s.QuoteAsset, Amount: s.QuoteAmount},
+			{Account: s.Buyer, Asset: s.QuoteAsset, Amount: -s.QuoteAmount},
+		},
+	}
+	if s.BuyerFee != 0 {
+		entry.Postings = append(entry.Postings,
+			Posting{Account: s.Buyer, Asset: s.BuyerFeeAsset, Amount: -s.BuyerFee},
+			Posting{Account: venue, Asset: s.BuyerFeeAsset, Amount: s.BuyerFee})
+	}
+	if s.SellerFee != 0 {
+		entry.Postings = append(entry.Postings,
+			Posting{Account: s.Seller, Asset: s.SellerFeeAsset, Amount: -s.SellerFee},
+			Posting{Account: venue, Asset: s.SellerFeeAsset, Amount: s.SellerFee})
+	}
+	// Postings are built balanced, so Post cannot reject them
+	me.ledger.Post(entry)
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestLedger_PostsEveryFill(t *testing.T) {
+	ledger := NewInMemoryLedger()
+	am := NewDefaultInMemoryAccountManager()
+	me := NewMatchingEngine(
+		WithClock(NewManualClock(0)),
+		WithInstrument(Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", QuantityScale: 1}),
+		WithAccountManager(am),
+		WithFeeSchedule(FeeSchedule{
+			Tiers: []FeeTier{
+				{MinVolume: 5000, MakerRate: -100, TakerRate: 500},
+				{MinVolume: 0, MakerRate: 1000, TakerRate: 2000},
+			},
+			Currency: FeeInQuote,
+		}),
+		WithLedger(ledger),
+	)
+	me.Deposit("alice", "BTC", 1000)
+	me.Deposit("bob", "USD", 1000000)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 1000, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "bob", Price: 1000, Size: 10, Side: orderbook.Buy, Timestamp: 2})
+	// Second fill earns alice a rebate
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 1000, Size: 10, Side: orderbook.Sell, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "bob", Price: 1000, Size: 10, Side: orderbook.Buy, Timestamp: 4})
+
+	entries := ledger.Entries("alice", 0, 10)
+	if len(entries) != 3 || entries[0].Seq != 1 || entries[2].MakerOrderID != 3 {
+		t.Fatalf("Expected a deposit and two fills for alice, got %+v", entries)
+	}
+	if got := ledger.Entries("alice", 3, 10); len(got) != 1 || got[0].Timestamp != 4 {
+		t.Fatalf("Expected one entry after time 3, got %+v", got)
+	}
+
+	// The ledger mirrors the account balances, fees landing in the same default fee account
+	for _, c := range []struct {
+		account, asset string
+		want           int64
+	}{
+		{"alice", "BTC", 1000 - 20},
+		{"alice", "USD", 20000 - 10 + 1},
+		{"bob", "BTC", 20},
+		{"bob", "USD", 1000000 - 20000 - 20 - 5},
+		{DefaultFeeAccount, "USD", 10 + 20 - 1 + 5},
+		{ExternalAccount, "BTC", -1000},
+	} {
+		if got := ledger.Balance(c.account, c.asset); got != c.want {
+			t.Errorf("%s %s: expected %d, got %d", c.account, c.asset, c.want, got)
+		}
+	}
+	if err := ledger.Reconcile(am); err != nil {
+		t.Errorf("Ledger should reconcile: %v", err)
+	}
+
+	// Funds moved behind the ledger's back are detected
+	am.Deposit("alice", "USD", 1)
+	if err := ledger.Reconcile(am); !errors.Is(err, ErrLedgerImbalance) {
+		t.Errorf("Expected ErrLedgerImbalance, got %v", err)
+	}
+}
+
+func TestLedger_RejectsUnbalancedEntry(t *testing.T) {
+	ledger := NewInMemoryLedger()
+	err := ledger.Post(JournalEntry{Postings: []Posting{{Account: "alice", Asset: "USD", Amount: 5}}})
+	if !errors.Is(err, ErrUnbalancedEntry) {
+		t.Fatalf("Expected ErrUnbalancedEntry, got %v", err)
+	}
+	if ledger.Balance("alice", "USD") != 0 {
+		t.Errorf("Rejected entry must not be applied")
+	}
+}
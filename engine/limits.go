@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"time"
+)
+
+// LimitConfig configures per-user open order caps and message throttling.
+// Zero values disable the corresponding limit.
+type LimitConfig struct {
+	// MaxOpenOrders caps the resting orders a user may have in the book
+	MaxOpenOrders int
+	// MaxOpenOrdersPerLevel caps the resting orders a user may have at one side and price
+	MaxOpenOrdersPerLevel int
+
+	// PlaceRate is the sustained number of place messages allowed per second, PlaceBurst the bucket size
+	PlaceRate  int64
+	PlaceBurst int64
+	// CancelRate is the sustained number of cancel messages allowed per second, CancelBurst the bucket size
+	CancelRate  int64
+	CancelBurst int64
+}
+
+// WithLimits enables open order caps and rate limiting
+func WithLimits(cfg LimitConfig) Option {
+	return func(me *MatchingEngine) {
+		me.limits = &cfg
+	}
+}
+
+// tokenBucket is an integer token bucket; tokens are scaled by 1e9 so refill is exact per nanosecond
+type tokenBucket struct {
+	tokens int64
+	last   int64
+}
+
+const tokenUnit = int64(time.Second)
+
+// take refills the bucket up to now and consumes one token if available
+func (b *tokenBucket) take(now, rate, burst int64) bool {
+	capacity := burst * tokenUnit
+	if elapsed := now - b.last; elapsed > 0 {
+		// Cap elapsed before multiplying to stay clear of overflow
+		if elapsed > capacity/rate+1 {
+			elapsed = capacity/rate + 1
+		}
+		b.tokens += elapsed * rate
+		if b.tokens > capacity {
+			b.tokens = capacity
+		}
+		b.last = now
+	}
+	if b.tokens < tokenUnit {
+		return false
+	}
+	b.tokens -= tokenUnit
+	return true
+}
+
+// levelKey identifies a user's orders at one side and price
+type levelKey struct {
+	userID string
+	side   orderbook.Side
+	price  int64
+}
+
+// throttle applies the user's token bucket for place (or cancel) messages
+func (me *MatchingEngine) throttle(userID string, cancel bool) error {
+	if me.limits == nil {
+		return nil
+	}
+	rate, burst, buckets, err := me.limits.PlaceRate, me.limits.PlaceBurst, me.placeBuckets, ErrPlaceRateLimited
+	if cancel {
+		rate, burst, buckets, err = me.limits.CancelRate, me.limits.CancelBurst, me.cancelBuckets, ErrCancelRateLimited
+	}
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	b, ok := buckets[userID]
+	if !ok {
+		now := me.clock.Now()
+		b = &tokenBucket{tokens: burst * tokenUnit, last: now}
+		buckets[userID] = b
+	}
+	if !b.take(me.clock.Now(), rate, burst) {
+		return err
+	}
+	return nil
+}
+
+// checkOpenOrderLimits rejects limit orders that would exceed the user's resting order caps
+func (me *MatchingEngine) checkOpenOrderLimits(order *orderbook.Order) error {
+	if me.limits == nil || order.Type != orderbook.Limit {
+		return nil
+	}
+	if me.limits.MaxOpenOrders > 0 && me.OpenOrderCount(order.UserID) >= me.limits.MaxOpenOrders {
+		return ErrTooManyOpenOrders
+	}
+	if me.limits.MaxOpenOrdersPerLevel > 0 &&
+		me.levelOrders[levelKey{order.UserID, order.Side, order.Price}] >= me.limits.MaxOpenOrdersPerLevel {
+		return ErrTooManyOpenOrdersAtLevel
+	}
+	return nil
+}
+
+// OpenOrderCount returns the number of resting orders the user has in the book
+func (me *MatchingEngine) OpenOrderCount(userID string) int {
+	return len(me.userOrders[userID])
+}
+
+// trackOpenOrder indexes an order that came to rest in the book
+func (me *MatchingEngine) trackOpenOrder(order *orderbook.Order) {
+	ids, ok := me.userOrders[order.UserID]
+	if !ok {
+		ids = make(map[uint64]struct{})
+		me.userOrders[order.UserID] = ids
+	}
+	ids[order.ID] = struct{}{}
+	me.levelOrders[levelKey{order.UserID, order.Side, order.Price}]++
+}
+
+// untrackOpenOrder removes an order that left the book from the per-user indexes
+func (me *MatchingEngine) untrackOpenOrder(order *orderbook.Order) {
+	ids, ok := me.userOrders[order.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := ids[order.ID]; !ok {
+		return
+	}
+	delete(ids, order.ID)
+	if len(ids) == 0 {
+		delete(me.userOrders, order.UserID)
+	}
+	key := levelKey{order.UserID, order.Side, order.Price}
+	if me.levelOrders[key] <= 1 {
+		delete(me.levelOrders, key)
+	} else {
+		me.levelOrders[key]--
+	}
+}
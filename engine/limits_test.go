@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+	"time"
+)
+
+func TestLimits_OpenOrderCaps(t *testing.T) {
+	me := NewMatchingEngine(WithLimits(LimitConfig{MaxOpenOrders: 3, MaxOpenOrdersPerLevel: 2}))
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "bot", Price: 100, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "bot", Price: 100, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "bot", Price: 100, Size: 1, Side: orderbook.Buy, Timestamp: 1}); !errors.Is(err, ErrTooManyOpenOrdersAtLevel) {
+		t.Errorf("Expected ErrTooManyOpenOrdersAtLevel, got %v", err)
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "bot", Price: 99, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 5, UserID: "bot", Price: 98, Size: 1, Side: orderbook.Buy, Timestamp: 1}); !errors.Is(err, ErrTooManyOpenOrders) {
+		t.Errorf("Expected ErrTooManyOpenOrders, got %v", err)
+	}
+	if me.OpenOrderCount("bot") != 3 {
+		t.Errorf("Expected 3 open orders, got %d", me.OpenOrderCount("bot"))
+	}
+
+	// Fills and cancels free up capacity
+	me.PlaceOrder(&orderbook.Order{ID: 6, UserID: "taker", Price: 100, Size: 1, Side: orderbook.Sell, Timestamp: 2})
+	me.CancelOrder(4)
+	if me.OpenOrderCount("bot") != 1 {
+		t.Errorf("Expected 1 open order, got %d", me.OpenOrderCount("bot"))
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 7, UserID: "bot", Price: 100, Size: 1, Side: orderbook.Buy, Timestamp: 3}); err != nil {
+		t.Errorf("Order should be accepted after capacity freed: %v", err)
+	}
+}
+
+func TestLimits_RateThrottle(t *testing.T) {
+	clock := NewManualClock(0)
+	me := NewMatchingEngine(WithClock(clock), WithLimits(LimitConfig{
+		PlaceRate: 2, PlaceBurst: 2,
+		CancelRate: 1, CancelBurst: 1,
+	}))
+
+	place := func(id uint64) error {
+		_, err := me.PlaceOrder(&orderbook.Order{ID: id, UserID: "bot", Price: 100, Size: 1, Side: orderbook.Buy})
+		return err
+	}
+	if err := place(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := place(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := place(3); !errors.Is(err, ErrPlaceRateLimited) {
+		t.Errorf("Expected ErrPlaceRateLimited, got %v", err)
+	}
+
+	// Other users have their own bucket
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "other", Price: 100, Size: 1, Side: orderbook.Buy}); err != nil {
+		t.Errorf("Other user should not be throttled: %v", err)
+	}
+
+	// 2 per second refills one token every 500ms
+	clock.Advance(500 * time.Millisecond)
+	if err := place(5); err != nil {
+		t.Errorf("Expected refill after 500ms: %v", err)
+	}
+
+	if err := me.CancelOrder(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := me.CancelOrder(2); !errors.Is(err, ErrCancelRateLimited) {
+		t.Errorf("Expected ErrCancelRateLimited, got %v", err)
+	}
+	if o, _ := me.OrderBook.GetOrder(5); o.Timestamp != int64(500*time.Millisecond) {
+		t.Errorf("Default timestamp should come from the engine clock, got %d", o.Timestamp)
+	}
+}
@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"math"
+	"orderbook-matching-engine/orderbook"
+	"sort"
+)
+
+// MarginConfig enables leveraged trading. Positions are margined against collateral held by the
+// engine: equity is collateral plus realized PnL plus unrealized PnL at the mark price, and
+// requirements are a share of the position notional at the mark price.
+// Fees are settled separately and do not count towards equity. Margined instruments settle
+// through positions and collateral and are not meant to be combined with an AccountManager.
+type MarginConfig struct {
+	// InitialBps is the margin required to open exposure, including resting orders
+	InitialBps int64
+	// MaintenanceBps is the margin below which a position is liquidated
+	MaintenanceBps int64
+	// InsuranceFund is the initial balance of the fund absorbing liquidation shortfalls
+	InsuranceFund int64
+}
+
+// WithMargin enables margin checks and liquidations
+func WithMargin(cfg MarginConfig) Option {
+	return func(me *MatchingEngine) {
+		me.margin = &cfg
+		me.insuranceFund = cfg.InsuranceFund
+	}
+}
+
+// liquidationIDBase starts the ID range of engine-generated liquidation orders
+const liquidationIDBase uint64 = 1 << 63
+
+// MarginAccount is a user's margin status at the current mark price
+type MarginAccount struct {
+	Collateral  int64 `json:"collateral"`
+	Equity      int64 `json:"equity"`      // Collateral + realized PnL + unrealized PnL
+	Initial     int64 `json:"initial"`     // Requirement to open the current position
+	Maintenance int64 `json:"maintenance"` // Liquidation threshold
+}
+
+// Liquidation describes the forced close of an under-margined position
+type Liquidation struct {
+	UserID    string `json:"user_id"`
+	OrderID   uint64 `json:"order_id"`   // IOC order closing the position
+	Size      int64  `json:"size"`       // Signed position before the liquidation
+	Closed    int64  `json:"closed"`     // Size executed by the liquidation order
+	MarkPrice int64  `json:"mark_price"` // Mark price that triggered the liquidation
+	Shortfall int64  `json:"shortfall"`  // Negative equity left after closing the position
+	Uncovered int64  `json:"uncovered"`  // Part of the shortfall the insurance fund could not absorb
+}
+
+// DepositCollateral credits margin collateral to a user
+func (me *MatchingEngine) DepositCollateral(userID string, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if me.reservedAccount(userID) {
+		return ErrReservedAccount
+	}
+	me.collateral[userID] += amount
+	return nil
+}
+
+// WithdrawCollateral debits margin collateral, keeping the initial margin of the user's exposure
+func (me *MatchingEngine) WithdrawCollateral(userID string, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if amount > me.collateral[userID] {
+		return ErrInsufficientFunds
+	}
+	if me.margin != nil {
+		acct := me.MarginAccount(userID)
+		long, short := me.openExposure(userID)
+		required := me.marginRequirement(me.markPrice, max(long, short), me.margin.InitialBps)
+		if acct.Equity-amount < required {
+			return ErrInsufficientMargin
+		}
+	}
+	me.collateral[userID] -= amount
+	return nil
+}
+
+// MarkPrice returns the price positions are margined at, or 0 if none was set
+func (me *MatchingEngine) MarkPrice() int64 {
+	return me.markPrice
+}
+
+// InsuranceFund returns the balance of the insurance fund
+func (me *MatchingEngine) InsuranceFund() int64 {
+	return me.insuranceFund
+}
+
+// MarginAccount returns the margin status of a user
+func (me *MatchingEngine) MarginAccount(userID string) MarginAccount {
+	pos := me.Position(userID)
+	acct := MarginAccount{
+		Collateral: me.collateral[userID],
+		Equity:     me.collateral[userID] + pos.RealizedPnL + me.unrealizedPnL(pos),
+	}
+	if me.margin != nil {
+		acct.Initial = me.marginRequirement(me.markPrice, abs(pos.Size), me.margin.InitialBps)
+		acct.Maintenance = me.marginRequirement(me.markPrice, abs(pos.Size), me.margin.MaintenanceBps)
+	}
+	return acct
+}
+
+// unrealizedPnL values a position at the mark price
+func (me *MatchingEngine) unrealizedPnL(pos Position) int64 {
+	if pos.Size == 0 || me.markPrice == 0 {
+		return 0
+	}
+	pnl, _ := me.notional(abs(me.markPrice-pos.EntryPrice), abs(pos.Size))
+	if (pos.Size > 0) != (me.markPrice > pos.EntryPrice) {
+		pnl = -pnl
+	}
+	return pnl
+}
+
+// marginRequirement returns bps of the notional of size at price
+func (me *MatchingEngine) marginRequirement(price, size, bps int64) int64 {
+	notional, ok := me.notionalCeil(price, size)
+	if !ok {
+		return math.MaxInt64
+	}
+	req, ok := mulDivCeil(notional, bps, BpsScale)
+	if !ok {
+		return math.MaxInt64
+	}
+	return req
+}
+
+// openExposure returns the long and short size a user would hold if all resting buys, or all
+// resting sells, lit and dark, filled. The larger of the two is the user's worst-case exposure.
+func (me *MatchingEngine) openExposure(userID string) (long, short int64) {
+	pos := me.Position(userID).Size
+	long, short = pos, -pos
+	for id := range me.userOrders[userID] {
+		o, ok := me.liveOrder(id)
+		if !ok || o.ReduceOnly {
+			continue
+		}
+		if o.Side == orderbook.Buy {
+			long += o.Size
+		} else {
+			short += o.Size
+		}
+	}
+	return long, short
+}
+
+// checkMargin rejects orders whose worst-case exposure the user's equity cannot cover
+// at the initial margin rate. Reduce-only orders are always allowed.
+func (me *MatchingEngine) checkMargin(order *orderbook.Order) error {
+	if me.margin == nil || order.ReduceOnly {
+		return nil
+	}
+	price := me.markPrice
+	if price == 0 {
+		price = order.Price
+	}
+	if price == 0 {
+		best := me.OrderBook.GetBestAsk()
+		if order.Side == orderbook.Sell {
+			best = me.OrderBook.GetBestBid()
+		}
+		if best != nil {
+			price = best.Price
+		}
+	}
+
+	long, short := me.openExposure(order.UserID)
+	if order.Side == orderbook.Buy {
+		long += order.Size
+	} else {
+		short += order.Size
+	}
+	required := me.marginRequirement(price, max(long, short), me.margin.InitialBps)
+	if me.MarginAccount(order.UserID).Equity < required {
+		return ErrInsufficientMargin
+	}
+	return nil
+}
+
+// SetMarkPrice sets the mark price directly, for venues that receive it from an external feed,
+// and liquidates every position it leaves under-margined. Returns the liquidations and their fills.
+func (me *MatchingEngine) SetMarkPrice(price int64) ([]Liquidation, []orderbook.MatchEvent, error) {
+	if price <= 0 {
+		return nil, nil, ErrInvalidMarkPrice
+	}
+	now := me.clock.Now()
+	me.markPrice = price
+	me.publishMark(MarkPriceUpdate{MarkPrice: price, IndexPrice: me.indexPrice}, now)
+	liqs, events := me.liquidate(now)
+	return liqs, events, nil
+}
+
+// UnderMargined returns the users whose equity is below the maintenance margin, sorted
+func (me *MatchingEngine) UnderMargined() []string {
+	if me.margin == nil || me.markPrice == 0 {
+		return nil
+	}
+	var users []string
+	for u, p := range me.positions {
+		if p.Size == 0 {
+			continue
+		}
+		if acct := me.MarginAccount(u); acct.Equity < acct.Maintenance {
+			users = append(users, u)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// liquidate force-closes under-margined positions in user order. Liquidations only run in
+// continuous trading; in other states positions wait for the next mark price.
+func (me *MatchingEngine) liquidate(now int64) ([]Liquidation, []orderbook.MatchEvent) {
+	if me.state != TradingContinuous {
+		return nil, nil
+	}
+	users := me.UnderMargined()
+	if len(users) == 0 {
+		return nil, nil
+	}
+	liqs := make([]Liquidation, 0, len(users))
+	events := orderbook.GetMatchEventSlice()
+	for _, u := range users {
+		l, evs := me.liquidateUser(u, now)
+		liqs = append(liqs, l)
+		events = append(events, evs...)
+	}
+	return liqs, me.processContingent(events, now)
+}
+
+// liquidateUser cancels a user's resting lit and dark orders, parked stops and unplaced bracket
+// exits, and closes the position with an IOC market order. A shortfall left once the position
+// is flat is covered by the insurance fund.
+func (me *MatchingEngine) liquidateUser(userID string, now int64) (Liquidation, []orderbook.MatchEvent) {
+	me.dropBrackets(userID)
+	for _, id := range me.userOrderIDs(userID) {
+		order, ok := me.liveOrder(id)
+		if !ok {
+			order, ok = me.stops.orders[id]
+		}
+		if !ok {
+			continue
+		}
+		report := execReport(order, orderbook.ExecCancelled, now)
+		if me.processCancelOrder(id) == nil || me.processCancelDarkOrder(id) == nil || me.cancelStop(id) == nil {
+			me.emitReports([]orderbook.ExecutionReport{report})
+		}
+	}
+
+	pos := me.Position(userID)
+	me.liquidationSeq++
+	order := orderbook.GetOrder()
+	order.ID = liquidationIDBase + me.liquidationSeq
+	order.UserID = userID
+	order.Type = orderbook.Market
+	order.Size = abs(pos.Size)
+	order.Side = orderbook.Sell
+	if pos.Size < 0 {
+		order.Side = orderbook.Buy
+	}
+	order.ReduceOnly = true
+	order.Timestamp = now
+
+	l := Liquidation{UserID: userID, OrderID: order.ID, Size: pos.Size, MarkPrice: me.markPrice}
+	events, _ := me.processPlaceOrder(order)
+	for _, ev := range events {
+		l.Closed += ev.Size
+	}
+	orderbook.PutOrder(order)
+
+	if me.Position(userID).Size == 0 {
+		if equity := me.MarginAccount(userID).Equity; equity < 0 {
+			l.Shortfall = -equity
+			covered := min(l.Shortfall, max(me.insuranceFund, 0))
+			me.insuranceFund -= covered
+			me.collateral[userID] += covered
+			l.Uncovered = l.Shortfall - covered
+		}
+	}
+	return l, events
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestMargin_InitialMarginAtEntry(t *testing.T) {
+	me := NewMatchingEngine(WithMargin(MarginConfig{InitialBps: 1000, MaintenanceBps: 500}))
+	me.DepositCollateral("alice", 120)
+	me.SetMarkPrice(100)
+
+	// 13 units at 100 need 130 of initial margin
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 100, Size: 13 * unit, Side: orderbook.Buy}); !errors.Is(err, ErrInsufficientMargin) {
+		t.Fatalf("Expected ErrInsufficientMargin, got %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "alice", Price: 100, Size: 12 * unit, Side: orderbook.Buy}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Resting orders count towards exposure
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 100, Size: unit, Side: orderbook.Buy}); !errors.Is(err, ErrInsufficientMargin) {
+		t.Fatalf("Expected ErrInsufficientMargin, got %v", err)
+	}
+	if err := me.WithdrawCollateral("alice", 1); !errors.Is(err, ErrInsufficientMargin) {
+		t.Fatalf("Withdrawal below initial margin should fail, got %v", err)
+	}
+}
+
+func TestMargin_Liquidation(t *testing.T) {
+	var reports []orderbook.ExecutionReport
+	me := NewMatchingEngine(
+		WithMargin(MarginConfig{InitialBps: 1000, MaintenanceBps: 500, InsuranceFund: 50}),
+		WithExecutionReportHandler(func(r orderbook.ExecutionReport) { reports = append(reports, r) }),
+	)
+	me.DepositCollateral("alice", 120)
+	me.DepositCollateral("bob", 1000)
+	me.DepositCollateral("carol", 1000)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "bob", Price: 100, Size: 10 * unit, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "alice", Price: 100, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 2})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 90, Size: unit, Side: orderbook.Buy, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "carol", Price: 85, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 4})
+
+	// Equity 50 against maintenance 47
+	if liqs, _, _ := me.SetMarkPrice(93); len(liqs) != 0 {
+		t.Fatalf("Expected no liquidation, got %+v", liqs)
+	}
+
+	// Equity 40 against maintenance 46
+	liqs, events, err := me.SetMarkPrice(92)
+	if err != nil || len(liqs) != 1 || len(events) != 1 {
+		t.Fatalf("Expected one liquidation, got %+v %v %v", liqs, events, err)
+	}
+	l := liqs[0]
+	if l.UserID != "alice" || l.Size != 10*unit || l.Closed != 10*unit || events[0].MakerOrderID != 4 {
+		t.Fatalf("Unexpected liquidation %+v %v", l, events)
+	}
+	if _, ok := me.OrderBook.GetOrder(3); ok {
+		t.Fatalf("Resting orders of a liquidated user should be cancelled")
+	}
+	if reports[0].OrderID != 3 || reports[0].Type != orderbook.ExecCancelled {
+		t.Errorf("Expected a cancel report for order 3, got %+v", reports)
+	}
+
+	// Closed at 85: -150 realized against 120 collateral, the fund covers the 30 shortfall
+	if l.Shortfall != 30 || l.Uncovered != 0 || me.InsuranceFund() != 20 {
+		t.Fatalf("Expected a 30 shortfall covered by the fund, got %+v fund %d", l, me.InsuranceFund())
+	}
+	if acct := me.MarginAccount("alice"); acct.Equity != 0 {
+		t.Errorf("Expected zero equity after the fund covered the shortfall, got %+v", acct)
+	}
+}
+
+func TestMargin_DarkOrders(t *testing.T) {
+	var reports []orderbook.ExecutionReport
+	me := NewMatchingEngine(
+		WithMargin(MarginConfig{InitialBps: 1000, MaintenanceBps: 500, InsuranceFund: 50}),
+		WithExecutionReportHandler(func(r orderbook.ExecutionReport) { reports = append(reports, r) }),
+	)
+	me.DepositCollateral("alice", 120)
+	me.DepositCollateral("bob", 1000)
+	me.DepositCollateral("carol", 1000)
+	me.SetMarkPrice(100)
+
+	if _, err := me.PlaceDarkOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 100, Size: 13 * unit, Side: orderbook.Buy, Timestamp: 1}); !errors.Is(err, ErrInsufficientMargin) {
+		t.Fatalf("Expected ErrInsufficientMargin for a dark order, got %v", err)
+	}
+	if _, err := me.PlaceDarkOrder(&orderbook.Order{ID: 2, UserID: "alice", Price: 90, Size: 2 * unit, Side: orderbook.Buy, Timestamp: 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The dark order counts towards exposure: 10 more fills the initial margin exactly, 11 exceed it
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 100, Size: 11 * unit, Side: orderbook.Buy, Timestamp: 3}); !errors.Is(err, ErrInsufficientMargin) {
+		t.Fatalf("Expected ErrInsufficientMargin, got %v", err)
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "bob", Price: 100, Size: 10 * unit, Side: orderbook.Sell, Timestamp: 4})
+	me.PlaceOrder(&orderbook.Order{ID: 5, UserID: "alice", Price: 100, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 5})
+	me.PlaceOrder(&orderbook.Order{ID: 6, UserID: "carol", Price: 85, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 6})
+
+	// Liquidation cancels the dark order along with the position
+	if liqs, _, _ := me.SetMarkPrice(92); len(liqs) != 1 || liqs[0].UserID != "alice" {
+		t.Fatalf("Expected alice liquidated, got %+v", liqs)
+	}
+	if _, ok := me.GetDarkOrder(2); ok {
+		t.Fatalf("Dark orders of a liquidated user should be cancelled")
+	}
+	if reports[0].OrderID != 2 || reports[0].Type != orderbook.ExecCancelled {
+		t.Errorf("Expected a cancel report for dark order 2, got %+v", reports)
+	}
+}
+
+func TestMargin_LiquidationCancelsStopsAndBrackets(t *testing.T) {
+	me := NewMatchingEngine(WithMargin(MarginConfig{InitialBps: 1000, MaintenanceBps: 500, InsuranceFund: 50}))
+	me.DepositCollateral("alice", 120)
+	me.DepositCollateral("bob", 1000)
+	me.DepositCollateral("carol", 1000)
+	me.DepositCollateral("dave", 1000)
+	me.SetMarkPrice(100)
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "bob", Price: 100, Size: 10 * unit, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "alice", Price: 100, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 2})
+	me.PlaceBracket(
+		&orderbook.Order{ID: 3, UserID: "alice", Price: 90, Size: unit, Side: orderbook.Buy, Timestamp: 3},
+		&orderbook.Order{ID: 4, UserID: "alice", Price: 120, Side: orderbook.Sell},
+		&orderbook.Order{ID: 5, UserID: "alice", Type: orderbook.Stop, StopPrice: 80, Side: orderbook.Sell},
+	)
+	me.PlaceOrder(&orderbook.Order{ID: 6, UserID: "dave", Price: 90, Size: unit / 2, Side: orderbook.Sell, Timestamp: 6})
+	me.PlaceOrder(&orderbook.Order{ID: 7, UserID: "alice", Type: orderbook.Stop, StopPrice: 130, Size: unit, Side: orderbook.Buy, Timestamp: 7})
+	me.PlaceOrder(&orderbook.Order{ID: 8, UserID: "carol", Price: 85, Size: 20 * unit, Side: orderbook.Buy, Timestamp: 8})
+
+	if liqs, _, _ := me.SetMarkPrice(92); len(liqs) != 1 || liqs[0].UserID != "alice" {
+		t.Fatalf("Expected alice liquidated, got %+v", liqs)
+	}
+	if len(me.stops.orders) != 0 || me.OpenOrderCount("alice") != 0 {
+		t.Fatalf("Liquidation should leave no parked stops or bracket exits, got %d stops", len(me.stops.orders))
+	}
+	if p := me.Position("alice"); p.Size != 0 {
+		t.Errorf("Expected a flat position, got %+v", p)
+	}
+}
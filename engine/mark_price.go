@@ -0,0 +1,132 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// MarkPriceConfig configures the mark price: the externally supplied index price plus a smoothed
+// basis between the book's impact midpoint and the index, clamped around the index. A single
+// trade or a thin book therefore cannot move the mark price far from the index.
+type MarkPriceConfig struct {
+	// ImpactNotional is the quote notional walked on each side of the book to compute the impact
+	// bid and ask; 0 uses the best bid and ask
+	ImpactNotional int64
+	// SmoothingBps is the weight of each new basis sample in its EMA, 0 = no smoothing
+	SmoothingBps int64
+	// ClampBps bounds the distance of the mark price from the index, 0 = unbounded
+	ClampBps int64
+}
+
+// WithMarkPrice configures how index price updates are turned into a mark price
+func WithMarkPrice(cfg MarkPriceConfig) Option {
+	return func(me *MatchingEngine) {
+		me.markConfig = cfg
+	}
+}
+
+// MarkPriceUpdate is published on the market data feed whenever the mark price changes
+type MarkPriceUpdate struct {
+	MarkPrice  int64 `json:"mark_price"`
+	IndexPrice int64 `json:"index_price"`
+	ImpactBid  int64 `json:"impact_bid"` // 0 when the side is thinner than the impact notional
+	ImpactAsk  int64 `json:"impact_ask"`
+	Basis      int64 `json:"basis"` // Smoothed impact midpoint minus index
+}
+
+// IndexPrice returns the last index price supplied, or 0
+func (me *MatchingEngine) IndexPrice() int64 {
+	return me.indexPrice
+}
+
+// UpdateIndexPrice records a new index price, recomputes the mark price from it and the book,
+// and liquidates every position the new mark price leaves under-margined.
+// The book's impact midpoint is sampled on each update; while a side is too thin the previous
+// basis is kept. Each update is also a premium sample for funding, skipped while a side is too thin.
+func (me *MatchingEngine) UpdateIndexPrice(price int64) ([]Liquidation, []orderbook.MatchEvent, error) {
+	if price <= 0 {
+		return nil, nil, ErrInvalidIndexPrice
+	}
+	me.indexPrice = price
+
+	now := me.clock.Now()
+	bid, ask := me.impactPrices()
+	me.samplePremium(bid, ask, now)
+	if bid > 0 && ask > 0 {
+		sample := bid + (ask-bid)/2 - price
+		weight := me.markConfig.SmoothingBps
+		if !me.basisSet || weight <= 0 || weight >= BpsScale {
+			me.basis = sample
+		} else {
+			delta, _ := mulDivSigned(sample-me.basis, weight, BpsScale)
+			me.basis += delta
+		}
+		me.basisSet = true
+	}
+
+	mark := price + me.basis
+	if me.markConfig.ClampBps > 0 {
+		band, _ := mulDiv(price, me.markConfig.ClampBps, BpsScale)
+		mark = min(max(mark, price-band), price+band)
+	}
+	mark = max(mark, 1)
+
+	me.markPrice = mark
+	me.publishMark(MarkPriceUpdate{MarkPrice: mark, IndexPrice: price, ImpactBid: bid, ImpactAsk: ask, Basis: me.basis}, now)
+	liqs, events := me.liquidate(now)
+	return liqs, events, nil
+}
+
+// impactPrices returns the average price of selling and of buying the impact notional against
+// the book, 0 for a side that cannot absorb it
+func (me *MatchingEngine) impactPrices() (bid, ask int64) {
+	return me.impactPrice(orderbook.Buy), me.impactPrice(orderbook.Sell)
+}
+
+// impactPrice walks the resting orders on a side until the impact notional is reached
+func (me *MatchingEngine) impactPrice(side orderbook.Side) int64 {
+	levels := me.OrderBook.Bids
+	if side == orderbook.Sell {
+		levels = me.OrderBook.Asks
+	}
+	target := me.markConfig.ImpactNotional
+	var filledSize, filledNotional, price int64
+	levels.Range(func(_ int64, value interface{}) bool {
+		q := value.(*orderbook.OrderQueue)
+		if q.Head == nil {
+			return true
+		}
+		price = q.Head.Price
+		if target <= 0 {
+			return false
+		}
+		size := int64(0)
+		for o := q.Head; o != nil; o = o.Next {
+			size += o.Size
+		}
+		notional, ok := me.notional(price, size)
+		if ok && filledNotional+notional < target {
+			filledSize += size
+			filledNotional += notional
+			return true
+		}
+		// This level completes the walk
+		rest, _ := mulDiv(target-filledNotional, me.instrument.QuantityScale, price)
+		filledSize += rest
+		filledNotional = target
+		return false
+	})
+	if target <= 0 {
+		return price
+	}
+	if filledNotional < target || filledSize == 0 {
+		return 0
+	}
+	avg, _ := mulDiv(filledNotional, me.instrument.QuantityScale, filledSize)
+	return avg
+}
+
+// publishMark sends a mark price update to the market data feed
+func (me *MatchingEngine) publishMark(update MarkPriceUpdate, now int64) {
+	if me.marketData == nil {
+		return
+	}
+	me.publish(MarketDataEvent{Type: MDMarkPrice, Timestamp: now, Mark: &update})
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestMarkPrice_ImpactPrices(t *testing.T) {
+	me := NewMatchingEngine(WithMarkPrice(MarkPriceConfig{ImpactNotional: 200}))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: unit, Side: orderbook.Sell})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 200, Size: unit, Side: orderbook.Sell})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 90, Size: unit, Side: orderbook.Buy})
+
+	// 1 unit at 100 plus 0.5 unit at 200; the bid side only holds 90 of notional
+	if bid, ask := me.impactPrices(); bid != 0 || ask != 133 {
+		t.Fatalf("Expected impact prices 0/133, got %d/%d", bid, ask)
+	}
+}
+
+func TestMarkPrice_SmoothedAndClamped(t *testing.T) {
+	feed := NewDefaultInMemoryMarketDataFeed()
+	me := NewMatchingEngine(
+		WithMarkPrice(MarkPriceConfig{ImpactNotional: 10_000, SmoothingBps: 5000, ClampBps: 100}),
+		WithMarketDataPublisher(feed),
+	)
+	if _, _, err := me.UpdateIndexPrice(0); !errors.Is(err, ErrInvalidIndexPrice) {
+		t.Fatalf("Expected ErrInvalidIndexPrice, got %v", err)
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 9900, Size: 2 * unit, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 10100, Size: 2 * unit, Side: orderbook.Sell})
+	feed.Drain()
+
+	// First sample: basis 10000 - 9950
+	me.UpdateIndexPrice(9950)
+	if me.MarkPrice() != 10000 {
+		t.Fatalf("Expected mark 10000, got %d", me.MarkPrice())
+	}
+
+	// Basis sample 200 smoothed to 125, mark clamped to 1% above the index
+	me.UpdateIndexPrice(9800)
+	if me.MarkPrice() != 9898 {
+		t.Fatalf("Expected mark clamped to 9898, got %d", me.MarkPrice())
+	}
+
+	events := feed.Drain()
+	if len(events) != 2 || events[1].Type != MDMarkPrice {
+		t.Fatalf("Expected two mark price events, got %v", events)
+	}
+	if m := events[1].Mark; m.MarkPrice != 9898 || m.IndexPrice != 9800 || m.Basis != 125 || m.ImpactBid != 9900 || m.ImpactAsk != 10100 {
+		t.Errorf("Unexpected mark price update %+v", m)
+	}
+}
+
+func TestMarkPrice_BasisFalls(t *testing.T) {
+	me := NewMatchingEngine(WithMarkPrice(MarkPriceConfig{SmoothingBps: 5000}))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 110, Size: unit, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 112, Size: unit, Side: orderbook.Sell})
+	me.UpdateIndexPrice(100)
+	if me.MarkPrice() != 111 {
+		t.Fatalf("Expected mark 111, got %d", me.MarkPrice())
+	}
+
+	// The book falls to a midpoint of 91: basis sample -9 smoothed from 11 to 1
+	me.CancelOrder(1)
+	me.CancelOrder(2)
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 90, Size: unit, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 92, Size: unit, Side: orderbook.Sell})
+	me.UpdateIndexPrice(100)
+	if me.MarkPrice() != 101 {
+		t.Fatalf("Expected mark 101, got %d", me.MarkPrice())
+	}
+
+	// And below the index: -9 smoothed from 1 to -4
+	me.UpdateIndexPrice(100)
+	if me.MarkPrice() != 96 {
+		t.Errorf("Expected mark 96, got %d", me.MarkPrice())
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// LeadMarketMakerPolicy layers designated liquidity provider incentives on top of FIFO matching.
+// At each level the incoming quantity is allocated to
+//  1. the top order, i.e. the order that opened the level by improving the price (if TopOrderPriority),
+//  2. the lead market maker's orders, up to AllocationBps of the quantity left after step 1,
+//  3. all remaining orders in time priority.
+//
+// Each price level a taker sweeps is allocated separately, so the lead market maker's share
+// applies at every level it quotes, not only at the best one.
+type LeadMarketMakerPolicy struct {
+	UserID           string // Designated lead market maker
+	AllocationBps    int64  // Share of each incoming quantity reserved for the lead market maker
+	TopOrderPriority bool
+}
+
+func (p LeadMarketMakerPolicy) Allocate(level *orderbook.OrderQueue, qty int64, dst []Allocation) []Allocation {
+	start := len(dst)
+	// give adds size to the order's allocation, merging repeated allocations to the same order
+	give := func(o *orderbook.Order, size int64) {
+		for i := start; i < len(dst); i++ {
+			if dst[i].Order == o {
+				dst[i].Size += size
+				return
+			}
+		}
+		dst = append(dst, Allocation{Order: o, Size: size})
+	}
+	remaining := func(o *orderbook.Order) int64 {
+		for i := start; i < len(dst); i++ {
+			if dst[i].Order == o {
+				return o.Size - dst[i].Size
+			}
+		}
+		return o.Size
+	}
+
+	// 1. Top order
+	if p.TopOrderPriority && level.Top != nil && qty > 0 {
+		size := min(level.Top.Size, qty)
+		give(level.Top, size)
+		qty -= size
+	}
+
+	// 2. Lead market maker share, in time priority across its orders
+	if p.UserID != "" && p.AllocationBps > 0 && qty > 0 {
+		share, _ := mulDiv(qty, p.AllocationBps, BpsScale)
+		for curr := level.Head; curr != nil && share > 0; curr = curr.Next {
+			if curr.UserID != p.UserID {
+				continue
+			}
+			size := min(remaining(curr), share)
+			if size > 0 {
+				give(curr, size)
+				share -= size
+				qty -= size
+			}
+		}
+	}
+
+	// 3. FIFO for the rest
+	for curr := level.Head; curr != nil && qty > 0; curr = curr.Next {
+		size := min(remaining(curr), qty)
+		if size > 0 {
+			give(curr, size)
+			qty -= size
+		}
+	}
+	return dst
+}
+
+// improvesBest reports whether an order about to rest would open a better price on its side
+func (me *MatchingEngine) improvesBest(order *orderbook.Order) bool {
+	if order.Side == orderbook.Buy {
+		best := me.OrderBook.GetBestBid()
+		return best == nil || order.Price > best.Price
+	}
+	best := me.OrderBook.GetBestAsk()
+	return best == nil || order.Price < best.Price
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sync"
+)
+
+// MarketDataEventType identifies the payload of a MarketDataEvent
+type MarketDataEventType int
+
+const (
+	// MDIndicativeUncross carries the indicative auction price and volume
+	MDIndicativeUncross MarketDataEventType = iota
+	// MDTradingState carries a trading state change
+	MDTradingState
+	// MDLevelUpdate carries the new aggregate size of a price level
+	MDLevelUpdate
+	// MDTrade carries a fill
+	MDTrade
+	// MDMarkPrice carries a mark price update
+	MDMarkPrice
+	// MDFunding carries a funding settlement
+	MDFunding
+)
+
+func (t MarketDataEventType) String() string {
+	switch t {
+	case MDIndicativeUncross:
+		return "IndicativeUncross"
+	case MDTradingState:
+		return "TradingState"
+	case MDLevelUpdate:
+		return "LevelUpdate"
+	case MDTrade:
+		return "Trade"
+	case MDMarkPrice:
+		return "MarkPrice"
+	case MDFunding:
+		return "Funding"
+	default:
+		return "Unknown"
+	}
+}
+
+// MarketDataEvent is a message on the engine's market data feed
+type MarketDataEvent struct {
+	Type         MarketDataEventType    `json:"type"`
+	Symbol       string                 `json:"symbol"`
+	Timestamp    int64                  `json:"timestamp"`
+	Indicative   *IndicativeUncross     `json:"indicative,omitempty"`
+	TradingState *TradingStateChange    `json:"trading_state,omitempty"`
+	Level        *orderbook.LevelUpdate `json:"level,omitempty"`
+	Trade        *orderbook.MatchEvent  `json:"trade,omitempty"`
+	Mark         *MarkPriceUpdate       `json:"mark,omitempty"`
+	Funding      *FundingEvent          `json:"funding,omitempty"`
+}
+
+// MarketDataPublisher defines the interface for the market data feed
+type MarketDataPublisher interface {
+	// Publish delivers an event; implementations must not call back into the engine
+	Publish(ev MarketDataEvent)
+}
+
+// InMemoryMarketDataFeed buffers published events in memory
+type InMemoryMarketDataFeed struct {
+	mu     sync.Mutex
+	events []MarketDataEvent
+}
+
+// NewDefaultInMemoryMarketDataFeed provides a thread-safe in-memory implementation
+func NewDefaultInMemoryMarketDataFeed() *InMemoryMarketDataFeed {
+	return &InMemoryMarketDataFeed{}
+}
+
+func (f *InMemoryMarketDataFeed) Publish(ev MarketDataEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, ev)
+}
+
+// Drain returns all buffered events and clears the buffer
+func (f *InMemoryMarketDataFeed) Drain() []MarketDataEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := f.events
+	f.events = nil
+	return events
+}
+
+// WithMarketDataPublisher attaches a market data feed to the engine
+func WithMarketDataPublisher(p MarketDataPublisher) Option {
+	return func(me *MatchingEngine) {
+		me.marketData = p
+	}
+}
+
+// publish sends an event to the feed, if one is attached
+func (me *MatchingEngine) publish(ev MarketDataEvent) {
+	if me.marketData == nil {
+		return
+	}
+	ev.Symbol = me.instrument.Symbol
+	if ev.Timestamp == 0 {
+		ev.Timestamp = me.clock.Now()
+	}
+	me.marketData.Publish(ev)
+}
+
+// publishLevels sends L2 level updates to the market data feed
+func (me *MatchingEngine) publishLevels(levels []orderbook.LevelUpdate, now int64) {
+	if me.marketData == nil {
+		return
+	}
+	for i := range levels {
+		l := levels[i]
+		me.publish(MarketDataEvent{Type: MDLevelUpdate, Timestamp: now, Level: &l})
+	}
+}
+
+// publishTrade sends a fill to the market data feed
+func (me *MatchingEngine) publishTrade(ev *orderbook.MatchEvent) {
+	if me.marketData == nil {
+		return
+	}
+	trade := *ev
+	me.publish(MarketDataEvent{Type: MDTrade, Timestamp: ev.Timestamp, Trade: &trade})
+}
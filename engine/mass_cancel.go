@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"math"
+	"orderbook-matching-engine/orderbook"
+	"sort"
+)
+
+// MassCancelResult lists the orders removed by a mass cancel and the resulting level sizes
+type MassCancelResult struct {
+	Cancelled []*orderbook.Order      `json:"cancelled"`
+	Levels    []orderbook.LevelUpdate `json:"levels"`
+}
+
+// CancelUserOrders cancels every resting and parked stop order owned by the user.
+// Like CancelOrder it is subject to the trading state and to the user's cancel throttle.
+func (me *MatchingEngine) CancelUserOrders(userID string) (*MassCancelResult, error) {
+	if !me.state.acceptsCancels() {
+		return nil, ErrCancelNotAllowed
+	}
+	if err := me.throttle(userID, true); err != nil {
+		return nil, err
+	}
+	return me.cancelOrders(me.userOrderIDs(userID), me.clock.Now()), nil
+}
+
+// CancelSide cancels every resting and parked stop order on one side of the book.
+// Venue-wide cancels are not throttled but respect the trading state.
+func (me *MatchingEngine) CancelSide(side orderbook.Side) (*MassCancelResult, error) {
+	return me.CancelPriceRange(side, math.MinInt64, math.MaxInt64)
+}
+
+// CancelPriceRange cancels every resting order on a side priced within [minPrice, maxPrice],
+// dark orders included (by limit price) and parked stops (by stop price)
+func (me *MatchingEngine) CancelPriceRange(side orderbook.Side, minPrice, maxPrice int64) (*MassCancelResult, error) {
+	if !me.state.acceptsCancels() {
+		return nil, ErrCancelNotAllowed
+	}
+	var ids []uint64
+	me.OrderBook.RangeOrders(side, minPrice, maxPrice, func(order *orderbook.Order) bool {
+		ids = append(ids, order.ID)
+		return true
+	})
+	dark := me.dark.buys
+	if side == orderbook.Sell {
+		dark = me.dark.sells
+	}
+	for _, o := range dark {
+		if o.Price >= minPrice && o.Price <= maxPrice {
+			ids = append(ids, o.ID)
+		}
+	}
+	ids = append(ids, me.stopIDs(func(o *orderbook.Order) bool {
+		return o.Side == side && o.StopPrice >= minPrice && o.StopPrice <= maxPrice
+	})...)
+	return me.cancelOrders(ids, me.clock.Now()), nil
+}
+
+// userOrderIDs returns the IDs of a user's resting and parked stop orders in ID order
+func (me *MatchingEngine) userOrderIDs(userID string) []uint64 {
+	ids := make([]uint64, 0, len(me.userOrders[userID]))
+	for id := range me.userOrders[userID] {
+		ids = append(ids, id)
+	}
+	ids = append(ids, me.stopIDs(func(o *orderbook.Order) bool { return o.UserID == userID })...)
+	// Map iteration order is random, cancel in ID order for deterministic output
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// stopIDs returns the IDs of the parked stops selected by keep, in ID order
+func (me *MatchingEngine) stopIDs(keep func(*orderbook.Order) bool) []uint64 {
+	var ids []uint64
+	for id, o := range me.stops.orders {
+		if keep(o) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// CancelAllOrders empties the book and the parked stops
+func (me *MatchingEngine) CancelAllOrders() (*MassCancelResult, error) {
+	result, err := me.CancelSide(orderbook.Buy)
+	if err != nil {
+		return nil, err
+	}
+	asks, _ := me.CancelSide(orderbook.Sell)
+	result.Cancelled = append(result.Cancelled, asks.Cancelled...)
+	result.Levels = append(result.Levels, asks.Levels...)
+	return result, nil
+}
+
+// cancelOrders cancels the given orders and reports each touched level once, in order of first touch.
+// Parked stop orders and dark orders are cancelled without a level update. Bracket exits activated by the
+// cancels are placed at now.
+func (me *MatchingEngine) cancelOrders(ids []uint64, now int64) *MassCancelResult {
+	result := &MassCancelResult{Cancelled: make([]*orderbook.Order, 0, len(ids))}
+	type level struct {
+		side  orderbook.Side
+		price int64
+	}
+	touched := make(map[level]struct{})
+	var levels []level
+
+	for _, id := range ids {
+		if stop, ok := me.stops.orders[id]; ok {
+			me.cancelStop(id)
+			result.Cancelled = append(result.Cancelled, stop)
+			continue
+		}
+		if order, ok := me.dark.remove(id); ok {
+			me.finishOrder(order)
+			result.Cancelled = append(result.Cancelled, order)
+			continue
+		}
+		order, ok := me.OrderBook.GetOrder(id)
+		if !ok {
+			continue
+		}
+		if err := me.processCancelOrder(id); err != nil {
+			continue
+		}
+		result.Cancelled = append(result.Cancelled, order)
+		l := level{order.Side, order.Price}
+		if _, ok := touched[l]; !ok {
+			touched[l] = struct{}{}
+			levels = append(levels, l)
+		}
+	}
+
+	result.Levels = make([]orderbook.LevelUpdate, 0, len(levels))
+	for _, l := range levels {
+		result.Levels = append(result.Levels, orderbook.LevelUpdate{
+			Side:  l.side,
+			Price: l.price,
+			Size:  me.OrderBook.LevelSize(l.side, l.price),
+		})
+	}
+	me.processContingent(nil, now)
+	return result
+}
@@ -2,22 +2,111 @@ package engine
 
 import (
 	"orderbook-matching-engine/orderbook"
-	"time"
 )
 
 const ()
 
 type MatchingEngine struct {
 	OrderBook *orderbook.OrderBook
+
+	instrument Instrument
+	accounts   AccountManager
+	fees       *FeeModel
+	risk       *RiskConfig
+	limits     *LimitConfig
+	clock      Clock
+
+	marketData MarketDataPublisher
+
+	sessionConfig SessionConfig
+	sessions      map[string]*Session // SessionID -> Session
+
+	reservations  map[uint64]*reservation        // OrderID -> funds held for the order
+	feeCarry      map[uint64]int64               // OrderID -> fee fraction prepaid by earlier fills
+	userOrders    map[string]map[uint64]struct{} // UserID -> resting OrderIDs
+	levelOrders   map[levelKey]int               // (UserID, Side, Price) -> resting order count
+	placeBuckets  map[string]*tokenBucket        // UserID -> place message throttle
+	cancelBuckets map[string]*tokenBucket        // UserID -> cancel message throttle
+
+	state    TradingState
+	schedule []ScheduledTransition // Pending calendar entries, ascending by time
+
+	policy   MatchingPolicy
+	allocBuf []Allocation // Reused allocation buffer for the matching policy
+
+	expiryMode    ExpiryClockMode
+	expiries      expiryIndex // Resting orders by ExpireAt
+	blockExpiries expiryIndex // Resting orders by ExpireBlock
+	reportHandler func(orderbook.ExecutionReport)
+
+	stops           stopBook
+	groups          map[uint64]*orderGroup // OrderID -> OCO/bracket group
+	pendingBrackets []*orderGroup          // Brackets whose entry is done, exits not yet placed
+	pegs            []uint64               // Resting pegged OrderIDs in placement order
+	dark            darkBook
+
+	positions        map[string]*Position
+	positionsChanged map[string]struct{} // Users whose resting reduce-only orders may need resizing
+
+	margin         *MarginConfig
+	markPrice      int64
+	collateral     map[string]int64 // UserID -> margin collateral
+	insuranceFund  int64
+	liquidationSeq uint64
+
+	markConfig MarkPriceConfig
+	indexPrice int64
+	basis      int64 // Smoothed impact midpoint minus index
+	basisSet   bool
+
+	funding        *FundingConfig
+	nextFunding    int64 // Next interval boundary, 0 until funding starts
+	premiumSum     int64
+	premiumSamples int64
+
+	ledger    Ledger
+	ledgerSeq uint64
+
+	trades        TradeStore
+	tradeSeq      uint64
+	tradeStoreErr error // First error returned by the trade store
+
+	blockHeight uint64
+	blockTime   int64
+
+	breaker              *CircuitBreakerConfig
+	staticReference      int64
+	volatilityAuctionEnd int64
+
+	lastTradePrice int64
 }
 
 // Option defines a functional option for configuring MatchingEngine
 type Option func(*MatchingEngine)
 
 // NewMatchingEngine creates a new matching engine
-func NewMatchingEngine() *MatchingEngine {
+func NewMatchingEngine(opts ...Option) *MatchingEngine {
 	me := &MatchingEngine{
-		OrderBook: orderbook.NewOrderBook(),
+		OrderBook:        orderbook.NewOrderBook(),
+		instrument:       DefaultInstrument(),
+		clock:            SystemClock(),
+		policy:           FIFOPolicy{},
+		reservations:     make(map[uint64]*reservation),
+		feeCarry:         make(map[uint64]int64),
+		userOrders:       make(map[string]map[uint64]struct{}),
+		levelOrders:      make(map[levelKey]int),
+		placeBuckets:     make(map[string]*tokenBucket),
+		cancelBuckets:    make(map[string]*tokenBucket),
+		sessions:         make(map[string]*Session),
+		stops:            newStopBook(),
+		dark:             newDarkBook(),
+		groups:           make(map[uint64]*orderGroup),
+		positions:        make(map[string]*Position),
+		positionsChanged: make(map[string]struct{}),
+		collateral:       make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(me)
 	}
 	return me
 }
@@ -27,32 +116,145 @@ func (me *MatchingEngine) Stop() {
 	// No background routines to stop
 }
 
+// PlaceOrder places an order. Stop and StopLimit orders wait off-book for their trigger.
+// The returned events include fills of any stop orders triggered by this order.
 func (me *MatchingEngine) PlaceOrder(order *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	events, err := me.placeOrder(order, true)
+	if err != nil {
+		return nil, err
+	}
+	return me.processContingent(events, order.Timestamp), nil
+}
+
+// placeOrder validates an order and either parks it (stops) or submits it to the book
+func (me *MatchingEngine) placeOrder(order *orderbook.Order, throttled bool) ([]orderbook.MatchEvent, error) {
 	// Validation
 	if order.ID == 0 {
 		return nil, ErrOrderIDNotSet
 	}
+	if me.reservedAccount(order.UserID) {
+		return nil, ErrReservedAccount
+	}
+	if order.QuoteSize != 0 {
+		if err := me.sizeQuoteOrder(order); err != nil {
+			return nil, err
+		}
+	}
 	if order.Size <= 0 {
 		return nil, ErrInvalidOrderSize
 	}
-	if order.Type == orderbook.Limit && order.Price <= 0 {
+	if lot := me.instrument.LotSize; lot > 1 && order.Size%lot != 0 {
+		return nil, ErrInvalidLotSize
+	}
+	if order.PegType != orderbook.PegNone {
+		if err := me.pricePeg(order); err != nil {
+			return nil, err
+		}
+	}
+	if (order.Type == orderbook.Limit || order.Type == orderbook.StopLimit) && order.Price <= 0 {
 		return nil, ErrInvalidLimitOrderPrice
 	}
+	if err := validateStop(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkTradingState(order); err != nil {
+		return nil, err
+	}
 
 	// Web3 deterministic requirement: Timestamp must be provided (e.g. block time)
 	// For off-chain matching, we allow flexible timestamp.
-	// If not provided (0), use local time (high performance, non-deterministic).
+	// If not provided (0), use the engine clock (local time by default, non-deterministic).
 	// If provided (e.g. from sequencer/block), use it (deterministic).
 	if order.Timestamp == 0 {
-		order.Timestamp = time.Now().UnixNano()
+		order.Timestamp = me.clock.Now()
 	}
 
+	if err := me.checkExpiry(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkSession(order); err != nil {
+		return nil, err
+	}
+
+	// Message throttling
+	if throttled {
+		if err := me.throttle(order.UserID, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if order.Type.IsStop() {
+		me.parkStop(order)
+		return orderbook.GetMatchEventSlice(), nil
+	}
+	return me.submitOrder(order)
+}
+
+// submitOrder runs the pre-trade checks of an order entering the book and matches it
+func (me *MatchingEngine) submitOrder(order *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	if err := me.preTradeChecks(order); err != nil {
+		return nil, err
+	}
 	return me.processPlaceOrder(order)
 }
 
+// preTradeChecks applies the checks shared by lit and dark orders and reserves the order's funds
+func (me *MatchingEngine) preTradeChecks(order *orderbook.Order) error {
+	// Reduce-only orders are sized to the position they close
+	if err := me.checkReduceOnly(order); err != nil {
+		return err
+	}
+
+	// Open order caps
+	if err := me.checkOpenOrderLimits(order); err != nil {
+		return err
+	}
+
+	// Pre-trade risk checks
+	if err := me.checkRisk(order); err != nil {
+		return err
+	}
+
+	// Initial margin on leveraged products
+	if err := me.checkMargin(order); err != nil {
+		return err
+	}
+
+	// Pre-trade fund reservation
+	if err := me.reserveFunds(order); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // CancelOrder executes the cancel logic directly
 func (me *MatchingEngine) CancelOrder(orderID uint64) error {
-	return me.processCancelOrder(orderID)
+	order, ok := me.OrderBook.GetOrder(orderID)
+	if !ok {
+		order, ok = me.stops.orders[orderID]
+	}
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if !me.state.acceptsCancels() {
+		return ErrCancelNotAllowed
+	}
+	if err := me.throttle(order.UserID, true); err != nil {
+		return err
+	}
+	if order.Type.IsStop() {
+		return me.cancelStop(orderID)
+	}
+	if err := me.processCancelOrder(orderID); err != nil {
+		return err
+	}
+	if me.state == TradingAuction {
+		me.publishIndicative()
+	}
+	// Cancelling a partially filled bracket entry activates its exits
+	me.processContingent(nil, me.clock.Now())
+	return nil
 }
 
 // GetDepth executes the depth retrieval directly
@@ -61,6 +263,10 @@ func (me *MatchingEngine) GetDepth(limit int) *orderbook.DepthSnapshot {
 }
 
 func (me *MatchingEngine) processPlaceOrder(order *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	if me.state == TradingAuction {
+		return me.processAuctionOrder(order)
+	}
+
 	// In Web3 context, this should come from the block timestamp, not system time
 	matchTime := order.Timestamp
 
@@ -68,26 +274,58 @@ func (me *MatchingEngine) processPlaceOrder(order *orderbook.Order) ([]orderbook
 	events := orderbook.GetMatchEventSlice()
 	matchCount := 0
 
+	// Worst acceptable price: the limit price, or the protection price for market orders
+	limitPrice, bounded := me.priceLimit(order)
+
+	// Volatility bands are anchored on prices before this order started matching
+	bandLow, bandHigh := me.priceBands()
+	breakerTripped := false
+
+	// Minimum quantity: the aggregate crossing fill must reach it or nothing trades.
+	// An order that cannot reach it is cancelled unless nothing crosses at all (a limit may then rest).
+	if need := minFillSize(order); need > 0 {
+		avail := me.crossableSize(order, need, limitPrice, bounded, bandLow, bandHigh)
+		if avail < need && (avail > 0 || order.Type != orderbook.Limit) {
+			me.finishOrder(order)
+			return nil, ErrMinQtyNotMet
+		}
+	}
+
+	// A limit whose remainder would rest crossing all-or-none makers it cannot fill is rejected
+	// before it trades, so the book never crosses
+	if order.Type == orderbook.Limit {
+		avail := me.crossableSize(order, order.Size, limitPrice, bounded, bandLow, bandHigh)
+		if avail < order.Size && me.crossingSize(order, limitPrice, bandLow, bandHigh) > avail {
+			me.finishOrder(order)
+			return nil, ErrAllOrNoneCross
+		}
+	}
+
 	// Matching Logic
-	for order.Size > 0 {
+	fundsExhausted := false
+	quoteLeft := order.QuoteSize // Notional a quote-sized order may still spend
+	skipLevels := 0              // Leading levels holding only all-or-none orders the taker cannot satisfy
+	for order.Size > 0 && !fundsExhausted && !breakerTripped {
 		var bestLevelQueue *orderbook.OrderQueue
 		var priceKey int64
 
 		// Find best price level Price priority
+		skip := skipLevels
+		findBest := func(key int64, value interface{}) bool {
+			if skip > 0 {
+				skip--
+				return true
+			}
+			bestLevelQueue = value.(*orderbook.OrderQueue)
+			priceKey = key
+			return false // Stop after first
+		}
 		if order.Side == orderbook.Buy {
 			// Buying: Look for lowest Sell (Ask)
-			me.OrderBook.Asks.Range(func(key int64, value interface{}) bool {
-				bestLevelQueue = value.(*orderbook.OrderQueue)
-				priceKey = key
-				return false // Stop after first
-			})
+			me.OrderBook.Asks.Range(findBest)
 		} else {
 			// Selling: Look for highest Buy (Bid)
-			me.OrderBook.Bids.Range(func(key int64, value interface{}) bool {
-				bestLevelQueue = value.(*orderbook.OrderQueue)
-				priceKey = key
-				return false // Stop after first
-			})
+			me.OrderBook.Bids.Range(findBest)
 		}
 
 		if bestLevelQueue == nil {
@@ -108,100 +346,177 @@ func (me *MatchingEngine) processPlaceOrder(order *orderbook.Order) ([]orderbook
 		}
 
 		// Check price crossing
-		if order.Type == orderbook.Limit {
+		if bounded {
 			if order.Side == orderbook.Buy {
-				if order.Price < bestLevelHead.Price {
+				if limitPrice < bestLevelHead.Price {
 					break
 				}
 			} else {
-				if order.Price > bestLevelHead.Price {
+				if limitPrice > bestLevelHead.Price {
 					break
 				}
 			}
 		}
 
-		// Batch matching at this price level
-		curr := bestLevelHead
-		// Note: We might modify curr pointer (curr = next) inside the loop
-		// So we need to handle linked list traversal and deletion correctly
-
-		// Record if the head node of current level has changed
-		levelHeadChanged := false
+		// Check volatility bands: stop at the band and interrupt continuous trading
+		if bestLevelHead.Price < bandLow || bestLevelHead.Price > bandHigh {
+			breakerTripped = true
+			break
+		}
 
-		for curr != nil && order.Size > 0 {
-			matchSize := order.Size
-			if curr.Size < matchSize {
-				matchSize = curr.Size
+		// Quantity the taker can take at this level
+		qty := me.affordableSize(order, bestLevelHead.Price, order.Size)
+		if order.QuoteSize > 0 {
+			qty = min(qty, me.sizeForQuote(quoteLeft, bestLevelHead.Price))
+		}
+		if qty == 0 {
+			// Taker cannot pay for another unit at this price
+			fundsExhausted = true
+			break
+		}
+		if order.ReduceOnly {
+			qty = min(qty, me.reducibleSize(order))
+			if qty == 0 {
+				// Position closed by earlier fills
+				break
 			}
+		}
 
-			events = append(events, orderbook.MatchEvent{
-				MakerOrderID: curr.ID,
-				TakerOrderID: order.ID,
-				Price:        curr.Price,
-				Size:         matchSize,
-				Timestamp:    matchTime,
-			})
+		// Batch matching at this price level, allocated by the matching policy
+		me.allocBuf = me.policy.Allocate(bestLevelQueue, qty, me.allocBuf[:0])
+		me.allocBuf = me.roundAllocations(bestLevelQueue, me.allocBuf)
+		me.allocBuf = me.constrainAllocations(bestLevelQueue, me.allocBuf)
+		matched := int64(0)
+		filled := 0
+		for _, a := range me.allocBuf {
+			if a.Size <= 0 {
+				continue
+			}
+			maker := a.Order
+			ev := me.newMatchEvent(maker, order, maker.Price, a.Size, matchTime)
+			me.observeTrade(ev.Price)
+			me.applyFees(&ev, maker, order)
+			me.settleFill(&ev, maker, order)
+			me.updatePositions(&ev, maker, order)
+			me.publishTrade(&ev)
+			me.recordTrade(&ev)
+			events = append(events, ev)
+			if order.QuoteSize > 0 {
+				spent, _ := me.notional(ev.Price, ev.Size)
+				quoteLeft -= spent
+			}
 			matchCount++
 
 			// Update sizes
-			order.Size -= matchSize
-			curr.Size -= matchSize
+			order.Size -= a.Size
+			maker.Size -= a.Size
+			matched += a.Size
+			me.onFill(maker, a.Size)
+			me.onFill(order, a.Size)
+			if maker.Size == 0 {
+				filled++
+			}
+		}
 
-			if curr.Size == 0 {
-				// Maker order filled
-				me.OrderBook.OrderMap.Delete(curr.ID)
+		// Remove filled makers from the level
+		me.removeFilled(bestLevelQueue, order.Side, priceKey, filled)
+		if matched == 0 {
+			// Nothing fillable at this level (all-or-none orders too large): try the next one
+			skipLevels++
+		}
+	}
 
-				// Move to next
-				next := curr.Next
-				curr.Next = nil // Help GC
+	// If remainder exists
+	if order.Size > 0 && order.Type == orderbook.Limit {
+		me.restOrder(order)
+	} else {
+		// Market Order remainder is cancelled (IOC)
+		me.finishOrder(order)
+	}
 
-				// Recycle object
-				orderbook.PutOrder(curr)
+	if breakerTripped {
+		me.tripCircuitBreaker(matchTime)
+	}
 
-				curr = next
+	return events, nil
+}
 
-				levelHeadChanged = true
-			} else {
-				// Maker partial fill -> Taker must be filled
-				break
-			}
+// removeFilled unlinks the given number of fully filled makers from a level of the book
+// opposite to takerSide, deleting the level once it is empty. Filled makers are recycled.
+func (me *MatchingEngine) removeFilled(q *orderbook.OrderQueue, takerSide orderbook.Side, priceKey int64, filled int) {
+	var prev *orderbook.Order
+	curr := q.Head
+	for curr != nil && filled > 0 {
+		next := curr.Next
+		if curr.Size > 0 {
+			prev = curr
+			curr = next
+			continue
 		}
 
-		// Update SkipMap
-		if curr == nil {
-			// Level exhausted
-			if order.Side == orderbook.Buy {
-				me.OrderBook.Asks.Delete(priceKey)
-			} else {
-				me.OrderBook.Bids.Delete(priceKey)
-			}
-		} else if levelHeadChanged {
-			// Head changed but level not exhausted
-			// Update the head of the list in SkipMap
-			// Since bestLevelQueue is a pointer to the value in the map, we can update it directly
-			bestLevelQueue.Head = curr
+		// Maker order filled
+		if prev == nil {
+			q.Head = next
+		} else {
+			prev.Next = next
+		}
+		if q.Tail == curr {
+			q.Tail = prev
 		}
+		if q.Top == curr {
+			q.Top = nil
+		}
+		me.OrderBook.OrderMap.Delete(curr.ID)
+		me.finishOrder(curr)
+		curr.Next = nil // Help GC
+		// Recycle object
+		orderbook.PutOrder(curr)
+
+		curr = next
+		filled--
 	}
 
-	// If remainder exists
-	if order.Size > 0 {
-		if order.Type == orderbook.Limit {
-			// Add to book
-			me.OrderBook.OrderMap.Store(order.ID, order)
-			me.OrderBook.AddMakerOrder(order)
+	if q.Head == nil {
+		// Level exhausted
+		if takerSide == orderbook.Buy {
+			me.OrderBook.Asks.Delete(priceKey)
+		} else {
+			me.OrderBook.Bids.Delete(priceKey)
 		}
-		// Market Order remainder is cancelled (IOC)
-		// Do nothing, just return events
 	}
+}
 
-	return events, nil
+// restOrder adds the remainder of an order to the book as a maker
+func (me *MatchingEngine) restOrder(order *orderbook.Order) {
+	improves := me.improvesBest(order)
+	me.OrderBook.AddMakerOrder(order)
+	if improves {
+		me.OrderBook.MarkTopOrder(order)
+	}
+	me.trackOpenOrder(order)
+	me.linkSessionOrder(order)
+	me.indexExpiry(order)
+	if order.PegType != orderbook.PegNone {
+		me.pegs = append(me.pegs, order.ID)
+	}
+	me.trimReservation(order)
 }
 
 // processCancelOrder is the internal cancel logic
 func (me *MatchingEngine) processCancelOrder(orderID uint64) error {
-	_, found := me.OrderBook.RemoveOrder(orderID)
+	order, found := me.OrderBook.RemoveOrder(orderID)
 	if !found {
 		return ErrOrderNotFound
 	}
+	me.finishOrder(order)
 	return nil
 }
+
+// finishOrder releases per-order engine state once an order is no longer live
+func (me *MatchingEngine) finishOrder(order *orderbook.Order) {
+	me.releaseFunds(order.ID)
+	delete(me.feeCarry, order.ID)
+	me.untrackOpenOrder(order)
+	me.unlinkSessionOrder(order)
+	me.onDone(order)
+}
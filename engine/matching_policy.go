@@ -0,0 +1,190 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// Allocation is the quantity a resting order receives from an incoming taker
+type Allocation struct {
+	Order *orderbook.Order
+	Size  int64
+}
+
+// MatchingPolicy decides how an incoming quantity is split across the orders of a price level
+type MatchingPolicy interface {
+	// Allocate appends to dst the fills for the orders of the level queue and returns it.
+	// Allocations must not exceed qty in total nor any order's size, and are applied in the returned order.
+	// The engine rounds allocations down to the instrument's lot size and allocates the residual in time priority.
+	Allocate(level *orderbook.OrderQueue, qty int64, dst []Allocation) []Allocation
+}
+
+// WithMatchingPolicy selects the level allocation algorithm (FIFOPolicy by default)
+func WithMatchingPolicy(p MatchingPolicy) Option {
+	return func(me *MatchingEngine) {
+		me.policy = p
+	}
+}
+
+// FIFOPolicy fills orders strictly in time priority
+type FIFOPolicy struct{}
+
+func (FIFOPolicy) Allocate(level *orderbook.OrderQueue, qty int64, dst []Allocation) []Allocation {
+	for curr := level.Head; curr != nil && qty > 0; curr = curr.Next {
+		size := min(curr.Size, qty)
+		dst = append(dst, Allocation{Order: curr, Size: size})
+		qty -= size
+	}
+	return dst
+}
+
+// ProRataPolicy fills orders in proportion to their size. Shares are rounded down and the
+// rounding residual is allocated in time priority. With MinAllocation set, shares smaller than
+// the minimum are withdrawn and their quantity is allocated in time priority as well.
+type ProRataPolicy struct {
+	MinAllocation int64
+}
+
+func (p ProRataPolicy) Allocate(level *orderbook.OrderQueue, qty int64, dst []Allocation) []Allocation {
+	total := int64(0)
+	for curr := level.Head; curr != nil; curr = curr.Next {
+		total += curr.Size
+	}
+	if total <= qty {
+		// Level is fully consumed
+		return FIFOPolicy{}.Allocate(level, qty, dst)
+	}
+
+	start := len(dst)
+	allocated := int64(0)
+	for curr := level.Head; curr != nil; curr = curr.Next {
+		share, _ := mulDiv(qty, curr.Size, total)
+		if share < p.MinAllocation {
+			share = 0
+		}
+		dst = append(dst, Allocation{Order: curr, Size: share})
+		allocated += share
+	}
+
+	// Residual in time priority, each order up to its remaining size
+	residual := qty - allocated
+	for i := start; i < len(dst) && residual > 0; i++ {
+		extra := min(dst[i].Order.Size-dst[i].Size, residual)
+		dst[i].Size += extra
+		residual -= extra
+	}
+	return dst
+}
+
+// roundAllocations rounds the policy's allocations down to the lot size and allocates the
+// rounding residual in whole lots in time priority
+func (me *MatchingEngine) roundAllocations(level *orderbook.OrderQueue, allocs []Allocation) []Allocation {
+	lot := me.instrument.LotSize
+	if lot <= 1 {
+		return allocs
+	}
+	residual := int64(0)
+	for i := range allocs {
+		r := allocs[i].Size % lot
+		allocs[i].Size -= r
+		residual += r
+	}
+	residual = me.roundLot(residual)
+	if residual == 0 {
+		return allocs
+	}
+
+	index := make(map[*orderbook.Order]int, len(allocs))
+	for i, a := range allocs {
+		index[a.Order] = i
+	}
+	for curr := level.Head; curr != nil && residual > 0; curr = curr.Next {
+		i, ok := index[curr]
+		have := int64(0)
+		if ok {
+			have = allocs[i].Size
+		}
+		size := me.roundLot(min(curr.Size-have, residual))
+		if size <= 0 {
+			continue
+		}
+		if ok {
+			allocs[i].Size += size
+		} else {
+			allocs = append(allocs, Allocation{Order: curr, Size: size})
+		}
+		residual -= size
+	}
+	return allocs
+}
+
+// constrainAllocations applies the order conditions policies are unaware of: all-or-none makers
+// only receive their full size and reduce-only makers no more than their owner can still reduce.
+// Withdrawn quantity goes to the other orders of the level in time priority.
+func (me *MatchingEngine) constrainAllocations(level *orderbook.OrderQueue, allocs []Allocation) []Allocation {
+	var caps map[string]int64 // Size each reduce-only owner can still reduce
+	capOf := func(o *orderbook.Order) int64 {
+		if caps == nil {
+			caps = make(map[string]int64)
+		}
+		left, ok := caps[o.UserID]
+		if !ok {
+			left = me.reducibleSize(o)
+			caps[o.UserID] = left
+		}
+		return left
+	}
+
+	freed := int64(0)
+	for i := range allocs {
+		a := &allocs[i]
+		if a.Size == 0 || (!a.Order.AllOrNone && !a.Order.ReduceOnly) {
+			continue
+		}
+		size := a.Size
+		if a.Order.ReduceOnly {
+			size = min(size, capOf(a.Order))
+		}
+		if a.Order.AllOrNone && size < a.Order.Size {
+			size = 0
+		}
+		if a.Order.ReduceOnly {
+			caps[a.Order.UserID] -= size
+		}
+		freed += a.Size - size
+		a.Size = size
+	}
+	if freed == 0 {
+		return allocs
+	}
+
+	index := make(map[*orderbook.Order]int, len(allocs))
+	for i, a := range allocs {
+		index[a.Order] = i
+	}
+	for curr := level.Head; curr != nil && freed > 0; curr = curr.Next {
+		i, ok := index[curr]
+		have := int64(0)
+		if ok {
+			have = allocs[i].Size
+		}
+		room := curr.Size - have
+		if curr.ReduceOnly {
+			room = min(room, capOf(curr))
+		}
+		if curr.AllOrNone && (have > 0 || room < curr.Size || curr.Size > freed) {
+			continue
+		}
+		size := min(room, freed)
+		if size <= 0 {
+			continue
+		}
+		if ok {
+			allocs[i].Size += size
+		} else {
+			allocs = append(allocs, Allocation{Order: curr, Size: size})
+		}
+		if curr.ReduceOnly {
+			caps[curr.UserID] -= size
+		}
+		freed -= size
+	}
+	return allocs
+}
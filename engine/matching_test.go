@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"errors"
 	"orderbook-matching-engine/orderbook"
 	"testing"
 )
@@ -197,3 +198,202 @@ func TestMatchingEngine_Validation(t *testing.T) {
 		t.Errorf("Market Order with Price 0 should be allowed, got error: %v", err)
 	}
 }
+
+func TestMatchingEngine_MassCancel(t *testing.T) {
+	me := NewMatchingEngine()
+	orders := []*orderbook.Order{
+		{ID: 1, UserID: "mm", Price: 99, Size: 10, Side: orderbook.Buy},
+		{ID: 2, UserID: "mm", Price: 98, Size: 10, Side: orderbook.Buy},
+		{ID: 3, UserID: "other", Price: 98, Size: 5, Side: orderbook.Buy},
+		{ID: 4, UserID: "mm", Price: 101, Size: 10, Side: orderbook.Sell},
+		{ID: 5, UserID: "other", Price: 102, Size: 5, Side: orderbook.Sell},
+		{ID: 6, UserID: "other", Price: 103, Size: 5, Side: orderbook.Sell},
+	}
+	for _, o := range orders {
+		o.Timestamp = 1000
+		if _, err := me.PlaceOrder(o); err != nil {
+			t.Fatalf("PlaceOrder failed: %v", err)
+		}
+	}
+
+	// Mass cancels follow the trading state like single cancels
+	me.SetTradingState(TradingHalted)
+	if _, err := me.CancelUserOrders("mm"); !errors.Is(err, ErrCancelNotAllowed) {
+		t.Fatalf("Expected ErrCancelNotAllowed while halted, got %v", err)
+	}
+	if _, err := me.CancelAllOrders(); !errors.Is(err, ErrCancelNotAllowed) {
+		t.Fatalf("Expected ErrCancelNotAllowed while halted, got %v", err)
+	}
+	me.SetTradingState(TradingContinuous)
+
+	// Cancel all of mm's orders
+	res, _ := me.CancelUserOrders("mm")
+	if len(res.Cancelled) != 3 || res.Cancelled[0].ID != 1 || res.Cancelled[1].ID != 2 || res.Cancelled[2].ID != 4 {
+		t.Fatalf("Unexpected cancelled orders: %v", res.Cancelled)
+	}
+	expected := []orderbook.LevelUpdate{
+		{Side: orderbook.Buy, Price: 99, Size: 0},
+		{Side: orderbook.Buy, Price: 98, Size: 5},
+		{Side: orderbook.Sell, Price: 101, Size: 0},
+	}
+	if len(res.Levels) != len(expected) {
+		t.Fatalf("Unexpected level updates: %v", res.Levels)
+	}
+	for i, l := range expected {
+		if res.Levels[i] != l {
+			t.Errorf("Level %d: expected %v, got %v", i, l, res.Levels[i])
+		}
+	}
+	if me.OpenOrderCount("mm") != 0 {
+		t.Errorf("mm should have no open orders")
+	}
+
+	// Price range on asks only touches [102, 102]
+	res, _ = me.CancelPriceRange(orderbook.Sell, 102, 102)
+	if len(res.Cancelled) != 1 || res.Cancelled[0].ID != 5 {
+		t.Errorf("Unexpected range cancel: %v", res.Cancelled)
+	}
+
+	// Cancel a side, then everything
+	res, _ = me.CancelSide(orderbook.Buy)
+	if len(res.Cancelled) != 1 || res.Cancelled[0].ID != 3 {
+		t.Errorf("Unexpected side cancel: %v", res.Cancelled)
+	}
+	res, _ = me.CancelAllOrders()
+	if len(res.Cancelled) != 1 || res.Cancelled[0].ID != 6 {
+		t.Errorf("Unexpected cancel all: %v", res.Cancelled)
+	}
+	depth := me.GetDepth(10)
+	if len(depth.Asks) != 0 || len(depth.Bids) != 0 {
+		t.Errorf("Book should be empty: %v", depth)
+	}
+}
+
+func TestMatchingEngine_ProRataPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   MatchingPolicy
+		expected map[uint64]int64
+	}{
+		// Shares 3.3/9.9/19.8 round down to 3/9/19, residual 2 goes to the oldest order
+		{"ProRata", ProRataPolicy{}, map[uint64]int64{1: 5, 2: 9, 3: 19}},
+		// Shares below 10 are withdrawn, the freed 14 is allocated in time priority
+		{"ProRataMinimum", ProRataPolicy{MinAllocation: 10}, map[uint64]int64{1: 10, 2: 4, 3: 19}},
+		{"FIFO", FIFOPolicy{}, map[uint64]int64{1: 10, 2: 23}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			me := NewMatchingEngine(WithMatchingPolicy(tt.policy))
+			me.OrderBook.AddMakerOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Sell})
+			me.OrderBook.AddMakerOrder(&orderbook.Order{ID: 2, Price: 100, Size: 30, Side: orderbook.Sell})
+			me.OrderBook.AddMakerOrder(&orderbook.Order{ID: 3, Price: 100, Size: 60, Side: orderbook.Sell})
+
+			events, err := me.PlaceOrder(&orderbook.Order{ID: 100, Price: 100, Size: 33, Side: orderbook.Buy, Timestamp: 1000})
+			if err != nil {
+				t.Fatalf("PlaceOrder failed: %v", err)
+			}
+			got := make(map[uint64]int64)
+			for _, e := range events {
+				got[e.MakerOrderID] += e.Size
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected fills %v, got %v", tt.expected, got)
+			}
+			for id, size := range tt.expected {
+				if got[id] != size {
+					t.Errorf("Order %d: expected %d, got %d", id, size, got[id])
+				}
+			}
+			// Level keeps time priority of the remaining orders
+			if depth := me.GetDepth(1); depth.Asks[0].Size != 67 {
+				t.Errorf("Expected 67 left at the level, got %v", depth.Asks)
+			}
+		})
+	}
+}
+
+func TestMatchingEngine_LeadMarketMakerAllocation(t *testing.T) {
+	me := NewMatchingEngine(WithMatchingPolicy(LeadMarketMakerPolicy{
+		UserID:           "lmm",
+		AllocationBps:    4000, // 40%
+		TopOrderPriority: true,
+	}))
+	makers := []*orderbook.Order{
+		{ID: 1, UserID: "a", Price: 101, Size: 10, Side: orderbook.Sell},
+		{ID: 2, UserID: "b", Price: 100, Size: 5, Side: orderbook.Sell}, // Improves the best ask: top order
+		{ID: 3, UserID: "c", Price: 100, Size: 10, Side: orderbook.Sell},
+		{ID: 4, UserID: "lmm", Price: 100, Size: 20, Side: orderbook.Sell},
+	}
+	for _, o := range makers {
+		o.Timestamp = 1000
+		me.PlaceOrder(o)
+	}
+
+	// 25 @ 100: top order takes 5, LMM gets 40% of the remaining 20 = 8, FIFO allocates 12 (10 to c, 2 to lmm)
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 10, Price: 100, Size: 25, Side: orderbook.Buy, Timestamp: 2000})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	expected := []struct {
+		maker uint64
+		size  int64
+	}{{2, 5}, {4, 10}, {3, 10}}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].MakerOrderID != e.maker || events[i].Size != e.size {
+			t.Errorf("Event %d: expected maker %d size %d, got %+v", i, e.maker, e.size, events[i])
+		}
+	}
+
+	// Top order filled: no more top priority at 100, LMM still gets its share
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 11, Price: 100, Size: 5, Side: orderbook.Buy, Timestamp: 3000})
+	if len(events) != 1 || events[0].MakerOrderID != 4 || events[0].Size != 5 {
+		t.Errorf("Unexpected allocation: %v", events)
+	}
+	if o, _ := me.OrderBook.GetOrder(4); o.Size != 5 {
+		t.Errorf("LMM order should have 5 left, got %d", o.Size)
+	}
+}
+
+func TestMatchingEngine_MassCancelParkedStops(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "mm", Type: orderbook.Stop, StopPrice: 110, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "other", Type: orderbook.Stop, StopPrice: 90, Size: 1, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "other", Type: orderbook.Stop, StopPrice: 80, Size: 1, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "other", Type: orderbook.Stop, StopPrice: 120, Size: 1, Side: orderbook.Buy, Timestamp: 1})
+
+	if res, _ := me.CancelUserOrders("mm"); len(res.Cancelled) != 1 || res.Cancelled[0].ID != 1 {
+		t.Fatalf("Expected mm's stop cancelled, got %v", res.Cancelled)
+	}
+	if res, _ := me.CancelPriceRange(orderbook.Sell, 85, 95); len(res.Cancelled) != 1 || res.Cancelled[0].ID != 2 {
+		t.Fatalf("Expected the stop priced at 90 cancelled, got %v", res.Cancelled)
+	}
+	if res, _ := me.CancelAllOrders(); len(res.Cancelled) != 2 || len(me.stops.orders) != 0 {
+		t.Fatalf("Expected the remaining stops cancelled, got %v", res.Cancelled)
+	}
+}
+
+func TestMatchingEngine_LeadMarketMakerShareAtEveryLevel(t *testing.T) {
+	me := NewMatchingEngine(WithMatchingPolicy(LeadMarketMakerPolicy{UserID: "lmm", AllocationBps: 5000}))
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "a", Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "lmm", Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 2})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "b", Price: 101, Size: 10, Side: orderbook.Sell, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "lmm", Price: 101, Size: 10, Side: orderbook.Sell, Timestamp: 4})
+
+	// 20 taken at 100 (the LMM's share capped by its order), then 50% of the 10 left at 101
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 10, Price: 101, Size: 30, Side: orderbook.Buy, Timestamp: 5})
+	expected := []struct {
+		maker uint64
+		size  int64
+	}{{2, 10}, {1, 10}, {4, 5}, {3, 5}}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].MakerOrderID != e.maker || events[i].Size != e.size {
+			t.Errorf("Event %d: expected maker %d size %d, got %+v", i, e.maker, e.size, events[i])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// minFillSize returns the aggregate fill a taker requires on entry, 0 if any fill is acceptable.
+// All-or-none takers require their full size.
+func minFillSize(order *orderbook.Order) int64 {
+	if order.AllOrNone {
+		return order.Size
+	}
+	return min(order.MinQty, order.Size)
+}
+
+// crossableSize returns how much an incoming order could fill right now, up to want, walking the
+// opposite side as the matcher would: within the price limit and volatility bands, skipping
+// all-or-none makers larger than what is left and stopping when a market buy runs out of funds.
+func (me *MatchingEngine) crossableSize(order *orderbook.Order, want, limitPrice int64, bounded bool, bandLow, bandHigh int64) int64 {
+	levels := me.OrderBook.Asks
+	if order.Side == orderbook.Sell {
+		levels = me.OrderBook.Bids
+	}
+	budget, budgeted := me.marketBuyBudget(order)
+
+	remaining := want
+	levels.Range(func(_ int64, value interface{}) bool {
+		q := value.(*orderbook.OrderQueue)
+		if q.Head == nil {
+			return true
+		}
+		price := q.Head.Price
+		if bounded && ((order.Side == orderbook.Buy && limitPrice < price) || (order.Side == orderbook.Sell && limitPrice > price)) {
+			return false
+		}
+		if price < bandLow || price > bandHigh {
+			return false
+		}
+		avail := remaining
+		if budgeted {
+			maxSize, _ := mulDiv(budget, me.instrument.QuantityScale, price)
+			avail = min(avail, maxSize)
+		}
+		take := int64(0)
+		for o := q.Head; o != nil && take < avail; o = o.Next {
+			if o.AllOrNone {
+				if o.Size <= avail-take {
+					take += o.Size
+				}
+				continue
+			}
+			take += min(o.Size, avail-take)
+		}
+		remaining -= take
+		if budgeted {
+			spent, _ := me.notionalCeil(price, take)
+			budget -= spent
+		}
+		return remaining > 0 && (!budgeted || budget > 0)
+	})
+	return want - remaining
+}
+
+// crossingSize returns the total size resting at prices an incoming limit order crosses,
+// within the volatility bands, whether or not it could be filled
+func (me *MatchingEngine) crossingSize(order *orderbook.Order, limitPrice, bandLow, bandHigh int64) int64 {
+	levels := me.OrderBook.Asks
+	if order.Side == orderbook.Sell {
+		levels = me.OrderBook.Bids
+	}
+	total := int64(0)
+	levels.Range(func(_ int64, value interface{}) bool {
+		q := value.(*orderbook.OrderQueue)
+		if q.Head == nil {
+			return true
+		}
+		price := q.Head.Price
+		if (order.Side == orderbook.Buy && limitPrice < price) || (order.Side == orderbook.Sell && limitPrice > price) {
+			return false
+		}
+		if price < bandLow || price > bandHigh {
+			return false
+		}
+		for o := q.Head; o != nil; o = o.Next {
+			total += o.Size
+		}
+		return true
+	})
+	return total
+}
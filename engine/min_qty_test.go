@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestAllOrNone_MakerSkippedWithoutLosingPriority(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, AllOrNone: true, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 100, Size: 5, Side: orderbook.Sell, Timestamp: 2})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 101, Size: 5, Side: orderbook.Sell, Timestamp: 3})
+
+	// Taker of 6 cannot satisfy order 1: it fills order 2 and continues to the next level
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 4, Price: 101, Size: 6, Side: orderbook.Buy, Timestamp: 4})
+	if len(events) != 2 || events[0].MakerOrderID != 2 || events[0].Size != 5 || events[1].MakerOrderID != 3 || events[1].Size != 1 {
+		t.Fatalf("Expected fills against orders 2 and 3, got %v", events)
+	}
+	if o, _ := me.OrderBook.GetOrder(1); o.Size != 10 {
+		t.Fatalf("All-or-none order must not be partially filled")
+	}
+
+	// A taker large enough fills it in full, ahead of the next level
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 5, Price: 101, Size: 12, Side: orderbook.Buy, Timestamp: 5})
+	if len(events) != 2 || events[0].MakerOrderID != 1 || events[0].Size != 10 || events[1].MakerOrderID != 3 {
+		t.Fatalf("Expected order 1 filled in full first, got %v", events)
+	}
+}
+
+func TestAllOrNone_ProRata(t *testing.T) {
+	me := NewMatchingEngine(WithMatchingPolicy(ProRataPolicy{}))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, AllOrNone: true, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 2})
+
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 3, Price: 100, Size: 8, Side: orderbook.Buy, Timestamp: 3})
+	if len(events) != 1 || events[0].MakerOrderID != 2 || events[0].Size != 8 {
+		t.Fatalf("Pro-rata share of the all-or-none order should go to order 2, got %v", events)
+	}
+}
+
+func TestMinQty_Taker(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 3, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 101, Size: 2, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 105, Size: 2, Side: orderbook.Sell, Timestamp: 1})
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, Price: 101, Size: 10, MinQty: 6, Side: orderbook.Buy, Timestamp: 2}); !errors.Is(err, ErrMinQtyNotMet) {
+		t.Fatalf("Expected ErrMinQtyNotMet, got %v", err)
+	}
+	if _, ok := me.OrderBook.GetOrder(4); ok {
+		t.Fatalf("Order without its minimum must not rest")
+	}
+	if o, _ := me.OrderBook.GetOrder(1); o.Size != 3 {
+		t.Fatalf("Nothing may trade when the minimum is not met")
+	}
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 5, Type: orderbook.Market, Size: 8, AllOrNone: true, Side: orderbook.Buy, Timestamp: 3}); !errors.Is(err, ErrMinQtyNotMet) {
+		t.Fatalf("All-or-none market taker should be rejected, got %v", err)
+	}
+
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 6, Price: 101, Size: 10, MinQty: 5, Side: orderbook.Buy, Timestamp: 4})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Expected minimum to be met, got %v %v", events, err)
+	}
+	if o, ok := me.OrderBook.GetOrder(6); !ok || o.Size != 5 {
+		t.Errorf("Remainder should rest")
+	}
+}
+
+func TestAllOrNone_SkippedMakerNeverLeavesBookCrossed(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, AllOrNone: true, Side: orderbook.Sell, Timestamp: 1})
+
+	// Limits that cannot fill the all-or-none ask and would rest crossing it are rejected
+	for i, price := range []int64{100, 101} {
+		events, err := me.PlaceOrder(&orderbook.Order{ID: uint64(2 + i), Price: price, Size: 5, Side: orderbook.Buy, Timestamp: 2})
+		if !errors.Is(err, ErrAllOrNoneCross) || len(events) != 0 {
+			t.Fatalf("Expected ErrAllOrNoneCross at %d, got %v %v", price, events, err)
+		}
+	}
+	if me.bookCrossed() {
+		t.Fatalf("Book must not be crossed: bid %v ask %v", me.OrderBook.GetBestBid(), me.OrderBook.GetBestAsk())
+	}
+
+	// A remainder that fits in front of the all-or-none ask still trades and rests
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 101, Size: 3, Side: orderbook.Sell, Timestamp: 3})
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 5, Price: 99, Size: 6, Side: orderbook.Buy, Timestamp: 4})
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Non-crossing bid should rest, got %v %v", events, err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 6, Price: 101, Size: 13, Side: orderbook.Buy, Timestamp: 5}); err != nil {
+		t.Fatalf("A buy filling every crossing order should be accepted, got %v", err)
+	}
+	if me.bookCrossed() {
+		t.Fatalf("Book must not be crossed")
+	}
+}
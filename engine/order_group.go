@@ -0,0 +1,217 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// orderGroup links contingent orders.
+// An OCO pair is a limit leg and a stop leg for the same size on the same side: fills of the
+// limit leg reduce the stop leg, the stop triggering cancels the limit leg, and either leg
+// leaving the book unfilled cancels the other.
+// A bracket is an entry order whose take-profit (limit) and stop-loss (stop) exits are
+// placed as an OCO pair, sized to the executed quantity, once the entry is done.
+type orderGroup struct {
+	limitID, stopID uint64 // OCO legs
+
+	entryID    uint64 // Bracket entry, 0 for a plain OCO pair
+	filled     int64  // Executed size of the entry
+	takeProfit *orderbook.Order
+	stopLoss   *orderbook.Order
+}
+
+// PlaceOCO places a one-cancels-other pair: a limit order and a Stop/StopLimit order for the
+// same user, side and size. Fills of the returned events include any stops they triggered.
+func (me *MatchingEngine) PlaceOCO(limit, stop *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	if err := validateLegs(limit, stop); err != nil {
+		return nil, err
+	}
+	if limit.Size != stop.Size {
+		return nil, ErrInvalidOrderGroup
+	}
+	events, err := me.placeOCO(limit, stop, true)
+	if err != nil {
+		return nil, err
+	}
+	return me.processContingent(events, limit.Timestamp), nil
+}
+
+// PlaceBracket places an entry order with take-profit and stop-loss exits. The exits are
+// inactive until the entry has filled (or is cancelled after a partial fill) and are then
+// placed as an OCO pair for the executed size; their Size is ignored.
+func (me *MatchingEngine) PlaceBracket(entry, takeProfit, stopLoss *orderbook.Order) ([]orderbook.MatchEvent, error) {
+	if entry.Type != orderbook.Limit && entry.Type != orderbook.Market {
+		return nil, ErrInvalidOrderGroup
+	}
+	if takeProfit.Side == entry.Side || takeProfit.UserID != entry.UserID {
+		return nil, ErrInvalidOrderGroup
+	}
+	if entry.ID == takeProfit.ID || entry.ID == stopLoss.ID {
+		return nil, ErrInvalidOrderGroup
+	}
+	if err := validateLegs(takeProfit, stopLoss); err != nil {
+		return nil, err
+	}
+	if _, ok := me.groups[entry.ID]; ok {
+		return nil, ErrInvalidOrderGroup
+	}
+
+	g := &orderGroup{entryID: entry.ID, takeProfit: takeProfit, stopLoss: stopLoss}
+	me.groups[entry.ID] = g
+	events, err := me.placeOrder(entry, true)
+	if err != nil {
+		delete(me.groups, entry.ID)
+		return nil, err
+	}
+	return me.processContingent(events, entry.Timestamp), nil
+}
+
+// validateLegs checks the shape of the legs of an OCO pair
+func validateLegs(limit, stop *orderbook.Order) error {
+	if limit.Type != orderbook.Limit || !stop.Type.IsStop() {
+		return ErrInvalidOrderGroup
+	}
+	if limit.ID == 0 || limit.ID == stop.ID || limit.UserID != stop.UserID || limit.Side != stop.Side {
+		return ErrInvalidOrderGroup
+	}
+	if limit.Price <= 0 {
+		return ErrInvalidLimitOrderPrice
+	}
+	if stop.Type == orderbook.StopLimit && stop.Price <= 0 {
+		return ErrInvalidLimitOrderPrice
+	}
+	return validateStop(stop)
+}
+
+// placeOCO parks the stop leg, then places the limit leg, undoing both if the limit leg is rejected.
+// Engine-generated pairs (bracket exits) are not subject to message throttling.
+func (me *MatchingEngine) placeOCO(limit, stop *orderbook.Order, throttled bool) ([]orderbook.MatchEvent, error) {
+	if _, ok := me.groups[limit.ID]; ok {
+		return nil, ErrInvalidOrderGroup
+	}
+	if _, ok := me.groups[stop.ID]; ok {
+		return nil, ErrInvalidOrderGroup
+	}
+	if stop.Timestamp == 0 {
+		stop.Timestamp = limit.Timestamp
+	}
+	if _, err := me.placeOrder(stop, throttled); err != nil {
+		return nil, err
+	}
+	g := &orderGroup{limitID: limit.ID, stopID: stop.ID}
+	me.groups[limit.ID] = g
+	me.groups[stop.ID] = g
+	events, err := me.placeOrder(limit, throttled)
+	if err != nil {
+		delete(me.groups, limit.ID)
+		delete(me.groups, stop.ID)
+		me.cancelStop(stop.ID)
+		return nil, err
+	}
+	return events, nil
+}
+
+// activateBracket places the exits of a bracket whose entry is done
+func (me *MatchingEngine) activateBracket(g *orderGroup, now int64) []orderbook.MatchEvent {
+	tp, sl := g.takeProfit, g.stopLoss
+	tp.Size, sl.Size = g.filled, g.filled
+	tp.Timestamp, sl.Timestamp = now, now
+	events, err := me.placeOCO(tp, sl, false)
+	if err != nil {
+		me.emitReports([]orderbook.ExecutionReport{
+			execReport(tp, orderbook.ExecRejected, now),
+			execReport(sl, orderbook.ExecRejected, now),
+		})
+		return nil
+	}
+	return events
+}
+
+// dropBrackets discards the exits of a user's bracket entries so that they are never placed
+func (me *MatchingEngine) dropBrackets(userID string) {
+	for id, g := range me.groups {
+		if id == g.entryID && g.takeProfit.UserID == userID {
+			delete(me.groups, id)
+		}
+	}
+	pending := me.pendingBrackets[:0]
+	for _, g := range me.pendingBrackets {
+		if g.takeProfit.UserID != userID {
+			pending = append(pending, g)
+		}
+	}
+	me.pendingBrackets = pending
+}
+
+// onFill applies a fill of an order to its group
+func (me *MatchingEngine) onFill(order *orderbook.Order, size int64) {
+	g, ok := me.groups[order.ID]
+	if !ok {
+		return
+	}
+	if order.ID == g.entryID {
+		g.filled += size
+		return
+	}
+	if order.ID != g.limitID {
+		return
+	}
+	// Limit leg filled: the stop leg protects only what is left
+	stop, ok := me.stops.orders[g.stopID]
+	if !ok {
+		return
+	}
+	stop.Size -= size
+	if stop.Size <= 0 {
+		delete(me.groups, g.limitID)
+		delete(me.groups, g.stopID)
+		me.stops.remove(stop.ID)
+		me.finishOrder(stop)
+		me.emitReports([]orderbook.ExecutionReport{execReport(stop, orderbook.ExecCancelled, me.clock.Now())})
+	}
+}
+
+// onTrigger cancels the limit leg of an OCO pair whose stop leg triggered
+func (me *MatchingEngine) onTrigger(stop *orderbook.Order, now int64) {
+	g, ok := me.groups[stop.ID]
+	if !ok {
+		return
+	}
+	delete(me.groups, g.limitID)
+	delete(me.groups, g.stopID)
+	me.cancelSibling(g.limitID, now)
+}
+
+// onDone updates the group of an order that is no longer live
+func (me *MatchingEngine) onDone(order *orderbook.Order) {
+	g, ok := me.groups[order.ID]
+	if !ok {
+		return
+	}
+	delete(me.groups, order.ID)
+	if order.ID == g.entryID {
+		if g.filled > 0 {
+			me.pendingBrackets = append(me.pendingBrackets, g)
+		}
+		return
+	}
+	// One leg left the book: the other one goes with it
+	sibling := g.limitID
+	if order.ID == g.limitID {
+		sibling = g.stopID
+	}
+	delete(me.groups, sibling)
+	me.cancelSibling(sibling, me.clock.Now())
+}
+
+// cancelSibling removes the other leg of an OCO pair, wherever it is, and reports it
+func (me *MatchingEngine) cancelSibling(orderID uint64, now int64) {
+	if order, ok := me.stops.remove(orderID); ok {
+		me.finishOrder(order)
+		me.emitReports([]orderbook.ExecutionReport{execReport(order, orderbook.ExecCancelled, now)})
+		return
+	}
+	if order, ok := me.OrderBook.GetOrder(orderID); ok {
+		report := execReport(order, orderbook.ExecCancelled, now)
+		if me.processCancelOrder(orderID) == nil {
+			me.emitReports([]orderbook.ExecutionReport{report})
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestStopOrder_Trigger(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 5, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 95, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Type: orderbook.Stop, StopPrice: 95, Size: 4, Side: orderbook.Sell, Timestamp: 1})
+
+	// Trade at 100 does not reach the stop
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 100, Size: 5, Side: orderbook.Sell, Timestamp: 2})
+	if _, ok := me.GetStopOrder(3); !ok {
+		t.Fatalf("Stop should still be waiting")
+	}
+
+	// Trade at 95 triggers the stop, which sells into the remaining bids
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 5, Price: 95, Size: 2, Side: orderbook.Sell, Timestamp: 3})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 2 || events[1].TakerOrderID != 3 || events[1].Size != 4 {
+		t.Fatalf("Expected triggered stop fill, got %v", events)
+	}
+	if o, _ := me.OrderBook.GetOrder(2); o.Size != 4 {
+		t.Errorf("Bid should have 4 left, got %d", o.Size)
+	}
+}
+
+func TestOCO_FillReducesAndTriggerCancels(t *testing.T) {
+	var reports []orderbook.ExecutionReport
+	me := NewMatchingEngine(WithExecutionReportHandler(func(r orderbook.ExecutionReport) { reports = append(reports, r) }))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 90, Size: 20, Side: orderbook.Buy, Timestamp: 1})
+
+	_, err := me.PlaceOCO(
+		&orderbook.Order{ID: 10, UserID: "u", Price: 110, Size: 10, Side: orderbook.Sell, Timestamp: 1},
+		&orderbook.Order{ID: 11, UserID: "u", Type: orderbook.Stop, StopPrice: 90, Size: 10, Side: orderbook.Sell},
+	)
+	if err != nil {
+		t.Fatalf("PlaceOCO failed: %v", err)
+	}
+
+	// Partial fill of the take-profit leg shrinks the stop leg
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 110, Size: 4, Side: orderbook.Buy, Timestamp: 2})
+	if stop, ok := me.GetStopOrder(11); !ok || stop.Size != 6 {
+		t.Fatalf("Stop leg should be reduced to 6")
+	}
+
+	// Trade at 90 triggers the stop leg and cancels the take-profit leg
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 3, Price: 90, Size: 1, Side: orderbook.Sell, Timestamp: 3})
+	if len(events) != 2 || events[1].TakerOrderID != 11 || events[1].Size != 6 {
+		t.Fatalf("Expected stop leg to sell 6, got %v", events)
+	}
+	if _, ok := me.OrderBook.GetOrder(10); ok {
+		t.Errorf("Take-profit leg should be cancelled")
+	}
+	if len(reports) != 2 || reports[0].Type != orderbook.ExecTriggered || reports[1].Type != orderbook.ExecCancelled || reports[1].OrderID != 10 {
+		t.Errorf("Unexpected reports: %v", reports)
+	}
+}
+
+func TestOCO_CancelLeg(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOCO(
+		&orderbook.Order{ID: 10, UserID: "u", Price: 110, Size: 10, Side: orderbook.Sell, Timestamp: 1},
+		&orderbook.Order{ID: 11, UserID: "u", Type: orderbook.StopLimit, StopPrice: 90, Price: 89, Size: 10, Side: orderbook.Sell},
+	)
+	if err := me.CancelOrder(11); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if _, ok := me.OrderBook.GetOrder(10); ok {
+		t.Errorf("Cancelling the stop leg should cancel the limit leg")
+	}
+
+	if _, err := me.PlaceOCO(
+		&orderbook.Order{ID: 20, UserID: "u", Price: 110, Size: 10, Side: orderbook.Sell},
+		&orderbook.Order{ID: 21, UserID: "u", Type: orderbook.Stop, StopPrice: 90, Size: 10, Side: orderbook.Buy},
+	); err != ErrInvalidOrderGroup {
+		t.Errorf("Expected ErrInvalidOrderGroup for legs on different sides, got %v", err)
+	}
+}
+
+func TestBracket_ExitsActivateOnEntryFill(t *testing.T) {
+	me := NewMatchingEngine()
+	_, err := me.PlaceBracket(
+		&orderbook.Order{ID: 1, UserID: "u", Price: 100, Size: 10, Side: orderbook.Buy, Timestamp: 1},
+		&orderbook.Order{ID: 2, UserID: "u", Price: 110, Side: orderbook.Sell},
+		&orderbook.Order{ID: 3, UserID: "u", Type: orderbook.Stop, StopPrice: 90, Side: orderbook.Sell},
+	)
+	if err != nil {
+		t.Fatalf("PlaceBracket failed: %v", err)
+	}
+	if _, ok := me.GetStopOrder(3); ok {
+		t.Fatalf("Exits must stay inactive until the entry fills")
+	}
+
+	// Partial fill: entry still working
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 100, Size: 6, Side: orderbook.Sell, Timestamp: 2})
+	if _, ok := me.OrderBook.GetOrder(2); ok {
+		t.Fatalf("Exits must stay inactive while the entry is working")
+	}
+
+	// Cancelling the entry activates the exits for the executed size
+	me.CancelOrder(1)
+	tp, ok := me.OrderBook.GetOrder(2)
+	if !ok || tp.Size != 6 {
+		t.Fatalf("Take-profit should rest with size 6")
+	}
+	if sl, ok := me.GetStopOrder(3); !ok || sl.Size != 6 {
+		t.Fatalf("Stop-loss should wait with size 6")
+	}
+
+	// Full fill of the take-profit removes the stop-loss
+	me.PlaceOrder(&orderbook.Order{ID: 5, Price: 110, Size: 6, Side: orderbook.Buy, Timestamp: 3})
+	if _, ok := me.GetStopOrder(3); ok {
+		t.Errorf("Stop-loss should be gone once the take-profit filled")
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// Pegged orders are limit orders priced at a reference (best bid, best ask or midpoint) plus
+// PegOffset, capped by PegLimit. References ignore other pegged orders so pegs never chase
+// each other. A pegged order never takes liquidity: its price is kept one unit inside the
+// opposite best price. After every command in continuous trading, pegs whose price moved are
+// re-queued at the back of their new level, in placement order.
+
+// pegReference returns the best price on a side among orders that are not pegged
+func (me *MatchingEngine) pegReference(side orderbook.Side) int64 {
+	levels := me.OrderBook.Bids
+	if side == orderbook.Sell {
+		levels = me.OrderBook.Asks
+	}
+	ref := int64(0)
+	levels.Range(func(_ int64, value interface{}) bool {
+		for o := value.(*orderbook.OrderQueue).Head; o != nil; o = o.Next {
+			if o.PegType == orderbook.PegNone {
+				ref = o.Price
+				return false
+			}
+		}
+		return true
+	})
+	return ref
+}
+
+// pegPrice computes the current price of a pegged order, or false if its reference is missing
+func (me *MatchingEngine) pegPrice(order *orderbook.Order) (int64, bool) {
+	var ref int64
+	switch order.PegType {
+	case orderbook.PegBestBid:
+		ref = me.pegReference(orderbook.Buy)
+	case orderbook.PegBestAsk:
+		ref = me.pegReference(orderbook.Sell)
+	case orderbook.PegMid:
+		bid, ask := me.pegReference(orderbook.Buy), me.pegReference(orderbook.Sell)
+		if bid == 0 || ask == 0 {
+			return 0, false
+		}
+		// Round away from the opposite side
+		ref = bid + (ask-bid)/2
+		if order.Side == orderbook.Sell {
+			ref = ask - (ask-bid)/2
+		}
+	}
+	if ref == 0 {
+		return 0, false
+	}
+
+	price := ref + order.PegOffset
+	if order.Side == orderbook.Buy {
+		if order.PegLimit > 0 {
+			price = min(price, order.PegLimit)
+		}
+		if ask := me.OrderBook.GetBestAsk(); ask != nil {
+			price = min(price, ask.Price-1)
+		}
+	} else {
+		if order.PegLimit > 0 {
+			price = max(price, order.PegLimit)
+		}
+		if bid := me.OrderBook.GetBestBid(); bid != nil {
+			price = max(price, bid.Price+1)
+		}
+	}
+	if price <= 0 {
+		return 0, false
+	}
+	return price, true
+}
+
+// pricePeg sets the initial price of a pegged order
+func (me *MatchingEngine) pricePeg(order *orderbook.Order) error {
+	if order.Type != orderbook.Limit {
+		return ErrInvalidPeg
+	}
+	price, ok := me.pegPrice(order)
+	if !ok {
+		return ErrNoPegReference
+	}
+	order.Price = price
+	return nil
+}
+
+// repeg moves every resting pegged order whose price changed
+func (me *MatchingEngine) repeg() {
+	live := me.pegs[:0]
+	for _, id := range me.pegs {
+		order, ok := me.OrderBook.GetOrder(id)
+		if !ok || order.PegType == orderbook.PegNone {
+			continue
+		}
+		if price, ok := me.pegPrice(order); ok && price != order.Price {
+			if !me.movePeg(order, price) {
+				continue
+			}
+		}
+		live = append(live, id)
+	}
+	clear(me.pegs[len(live):])
+	me.pegs = live
+}
+
+// movePeg re-queues a pegged order at a new price, cancelling it if the owner cannot fund the move
+func (me *MatchingEngine) movePeg(order *orderbook.Order, price int64) bool {
+	old := order.Price
+	order.Price = price
+	if err := me.resizeReservation(order); err != nil {
+		order.Price = old
+		report := execReport(order, orderbook.ExecCancelled, me.clock.Now())
+		if me.processCancelOrder(order.ID) == nil {
+			me.emitReports([]orderbook.ExecutionReport{report})
+		}
+		return false
+	}
+	order.Price = old
+	me.OrderBook.RemoveOrder(order.ID)
+	me.untrackOpenOrder(order)
+	order.Price = price
+	me.OrderBook.AddMakerOrder(order)
+	me.trackOpenOrder(order)
+	return true
+}
+
+// resizeReservation adjusts the funds held for a resting order to what it needs at its current price
+func (me *MatchingEngine) resizeReservation(order *orderbook.Order) error {
+	res, ok := me.reservations[order.ID]
+	if !ok {
+		return nil
+	}
+	_, required, err := me.requiredReserve(order)
+	if err != nil {
+		return err
+	}
+	if required > res.amount {
+		if err := me.accounts.Reserve(res.userID, res.asset, required-res.amount); err != nil {
+			return err
+		}
+		res.amount = required
+		return nil
+	}
+	me.trimReservation(order)
+	return nil
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestPeg_BestBidWithCap(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 99, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 105, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+
+	peg := &orderbook.Order{ID: 3, PegType: orderbook.PegBestBid, PegOffset: 1, PegLimit: 102, Size: 5, Side: orderbook.Buy, Timestamp: 2}
+	if _, err := me.PlaceOrder(peg); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if peg.Price != 100 {
+		t.Fatalf("Peg should price at 100, got %d", peg.Price)
+	}
+
+	// New best bid moves the peg, the cap stops it
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 101, Size: 1, Side: orderbook.Buy, Timestamp: 3})
+	if peg.Price != 102 {
+		t.Fatalf("Peg should follow to 102, got %d", peg.Price)
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 5, Price: 104, Size: 1, Side: orderbook.Buy, Timestamp: 4})
+	if peg.Price != 102 {
+		t.Fatalf("Peg should be capped at 102, got %d", peg.Price)
+	}
+
+	// Cancellations move it back
+	me.CancelOrder(5)
+	me.CancelOrder(4)
+	if peg.Price != 100 {
+		t.Fatalf("Peg should fall back to 100, got %d", peg.Price)
+	}
+}
+
+func TestPeg_RequeueLosesPriority(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 99, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, PegType: orderbook.PegBestBid, Size: 5, Side: orderbook.Buy, Timestamp: 2})
+
+	// Peg joins the new best bid behind it, then returns to 99 once it trades away
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 100, Size: 5, Side: orderbook.Buy, Timestamp: 3})
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 4, Price: 100, Size: 5, Side: orderbook.Sell, Timestamp: 4})
+	if len(events) != 1 || events[0].MakerOrderID != 3 {
+		t.Fatalf("Order 3 should have priority at 100, got %v", events)
+	}
+	if peg, _ := me.OrderBook.GetOrder(2); peg.Price != 99 {
+		t.Fatalf("Peg should be back at 99, got %d", peg.Price)
+	}
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 5, Price: 99, Size: 10, Side: orderbook.Sell, Timestamp: 5})
+	if len(events) != 1 || events[0].MakerOrderID != 1 {
+		t.Fatalf("Re-queued peg must be behind order 1, got %v", events)
+	}
+}
+
+func TestPeg_Midpoint(t *testing.T) {
+	me := NewMatchingEngine()
+	peg := &orderbook.Order{ID: 1, PegType: orderbook.PegMid, Size: 5, Side: orderbook.Sell, Timestamp: 1}
+	if _, err := me.PlaceOrder(peg); err != ErrNoPegReference {
+		t.Fatalf("Expected ErrNoPegReference, got %v", err)
+	}
+
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 99, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 105, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	if _, err := me.PlaceOrder(peg); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if peg.Price != 102 {
+		t.Fatalf("Mid peg should price at 102, got %d", peg.Price)
+	}
+
+	// Sell mid rounds up: (99+104)/2 -> 102
+	me.PlaceOrder(&orderbook.Order{ID: 4, Price: 104, Size: 1, Side: orderbook.Sell, Timestamp: 2})
+	if peg.Price != 102 {
+		t.Fatalf("Mid peg should stay at 102, got %d", peg.Price)
+	}
+	// Matching the best ask away moves the midpoint back
+	me.PlaceOrder(&orderbook.Order{ID: 5, Price: 104, Size: 1, Side: orderbook.Buy, Timestamp: 3})
+	if peg.Price != 102 {
+		t.Fatalf("Mid peg should be at 102, got %d", peg.Price)
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 6, Price: 101, Size: 1, Side: orderbook.Buy, Timestamp: 4})
+	if peg.Price != 103 {
+		t.Errorf("Mid peg should move to 103, got %d", peg.Price)
+	}
+}
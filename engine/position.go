@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sort"
+)
+
+// Position is a user's net exposure in the instrument, updated from every fill
+type Position struct {
+	Size        int64 `json:"size"`         // Signed base size: positive long, negative short
+	EntryPrice  int64 `json:"entry_price"`  // Average price of the open size
+	RealizedPnL int64 `json:"realized_pnl"` // Quote PnL of closed size, before fees
+}
+
+// Position returns the user's current position
+func (me *MatchingEngine) Position(userID string) Position {
+	if p, ok := me.positions[userID]; ok {
+		return *p
+	}
+	return Position{}
+}
+
+// updatePositions applies a fill to the buyer's and seller's positions
+func (me *MatchingEngine) updatePositions(ev *orderbook.MatchEvent, maker, taker *orderbook.Order) {
+	buyer, seller := taker, maker
+	if taker.Side == orderbook.Sell {
+		buyer, seller = maker, taker
+	}
+	me.applyPositionFill(buyer.UserID, ev.Price, ev.Size)
+	me.applyPositionFill(seller.UserID, ev.Price, -ev.Size)
+}
+
+// applyPositionFill adds a signed fill to a position, realizing PnL on the part that closes it
+func (me *MatchingEngine) applyPositionFill(userID string, price, size int64) {
+	p, ok := me.positions[userID]
+	if !ok {
+		p = &Position{}
+		me.positions[userID] = p
+	}
+	me.positionsChanged[userID] = struct{}{}
+
+	if p.Size == 0 || (p.Size > 0) == (size > 0) {
+		// Opening or increasing: volume-weighted entry price
+		open, add := abs(p.Size), abs(size)
+		total := open + add
+		a, _ := mulDiv(p.EntryPrice, open, total)
+		b, _ := mulDiv(price, add, total)
+		p.EntryPrice = a + b
+		p.Size += size
+		return
+	}
+
+	closed := min(abs(size), abs(p.Size))
+	pnl, _ := me.notional(abs(price-p.EntryPrice), closed)
+	if (p.Size > 0) != (price > p.EntryPrice) {
+		pnl = -pnl
+	}
+	if price == p.EntryPrice {
+		pnl = 0
+	}
+	p.RealizedPnL += pnl
+	p.Size += size
+	switch {
+	case p.Size == 0:
+		p.EntryPrice = 0
+	case (p.Size > 0) == (size > 0):
+		// Flipped: the remainder opens at the fill price
+		p.EntryPrice = price
+	}
+}
+
+// reducibleSize returns how much of an order's side would still reduce its owner's position
+func (me *MatchingEngine) reducibleSize(order *orderbook.Order) int64 {
+	p, ok := me.positions[order.UserID]
+	if !ok {
+		return 0
+	}
+	if order.Side == orderbook.Buy && p.Size < 0 {
+		return -p.Size
+	}
+	if order.Side == orderbook.Sell && p.Size > 0 {
+		return p.Size
+	}
+	return 0
+}
+
+// checkReduceOnly shrinks a reduce-only order to the position it can close, or rejects it
+func (me *MatchingEngine) checkReduceOnly(order *orderbook.Order) error {
+	if !order.ReduceOnly {
+		return nil
+	}
+	reducible := me.reducibleSize(order)
+	if reducible == 0 {
+		return ErrReduceOnly
+	}
+	order.Size = min(order.Size, reducible)
+	return nil
+}
+
+// closedReduceOnly returns whichever of two orders is reduce-only with nothing left to reduce
+func (me *MatchingEngine) closedReduceOnly(bid, ask *orderbook.Order) *orderbook.Order {
+	for _, o := range []*orderbook.Order{bid, ask} {
+		if o.ReduceOnly && me.reducibleSize(o) == 0 {
+			return o
+		}
+	}
+	return nil
+}
+
+// resizeReduceOnly shrinks the resting reduce-only orders, lit and dark, of users whose position changed,
+// cancelling those that can no longer reduce it
+func (me *MatchingEngine) resizeReduceOnly(now int64) {
+	if len(me.positionsChanged) == 0 {
+		return
+	}
+	users := make([]string, 0, len(me.positionsChanged))
+	for u := range me.positionsChanged {
+		users = append(users, u)
+	}
+	clear(me.positionsChanged)
+	sort.Strings(users)
+
+	for _, u := range users {
+		ids := make([]uint64, 0, len(me.userOrders[u]))
+		for id := range me.userOrders[u] {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			order, ok := me.liveOrder(id)
+			if !ok || !order.ReduceOnly {
+				continue
+			}
+			reducible := me.reducibleSize(order)
+			if reducible == 0 {
+				report := execReport(order, orderbook.ExecCancelled, now)
+				if me.processCancelOrder(id) == nil || me.processCancelDarkOrder(id) == nil {
+					me.emitReports([]orderbook.ExecutionReport{report})
+				}
+				continue
+			}
+			if order.Size > reducible {
+				order.Size = reducible
+				me.trimReservation(order)
+			}
+		}
+	}
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
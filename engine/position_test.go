@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+const unit = 1e8 // Default QuantityScale
+
+// fill trades size between buyer and seller at price, the seller resting
+func fill(me *MatchingEngine, id uint64, buyer, seller string, price, size int64) {
+	me.PlaceOrder(&orderbook.Order{ID: id, UserID: seller, Price: price, Size: size, Side: orderbook.Sell, Timestamp: int64(id)})
+	me.PlaceOrder(&orderbook.Order{ID: id + 1, UserID: buyer, Price: price, Size: size, Side: orderbook.Buy, Timestamp: int64(id)})
+}
+
+func TestPosition_EntryPriceAndPnL(t *testing.T) {
+	me := NewMatchingEngine()
+	fill(me, 1, "alice", "bob", 100, 10*unit)
+	fill(me, 3, "alice", "bob", 120, 10*unit)
+
+	if p := me.Position("alice"); p.Size != 20*unit || p.EntryPrice != 110 {
+		t.Fatalf("Expected long 20 at 110, got %+v", p)
+	}
+	if p := me.Position("bob"); p.Size != -20*unit || p.EntryPrice != 110 {
+		t.Fatalf("Expected short 20 at 110, got %+v", p)
+	}
+
+	// Alice sells 5 at 130: +20 per unit realized
+	fill(me, 5, "carol", "alice", 130, 5*unit)
+	if p := me.Position("alice"); p.Size != 15*unit || p.EntryPrice != 110 || p.RealizedPnL != 100 {
+		t.Fatalf("Expected long 15 at 110 with 100 realized, got %+v", p)
+	}
+
+	// Bob buys 25 at 100: closes 20 at +10 per unit, flips long 5 at 100
+	fill(me, 7, "bob", "dave", 100, 25*unit)
+	if p := me.Position("bob"); p.Size != 5*unit || p.EntryPrice != 100 || p.RealizedPnL != 200 {
+		t.Fatalf("Expected long 5 at 100 with 200 realized, got %+v", p)
+	}
+}
+
+func TestReduceOnly_Entry(t *testing.T) {
+	me := NewMatchingEngine()
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 100, Size: unit, ReduceOnly: true, Side: orderbook.Sell}); !errors.Is(err, ErrReduceOnly) {
+		t.Fatalf("Expected ErrReduceOnly without a position, got %v", err)
+	}
+
+	fill(me, 2, "alice", "bob", 100, 10*unit)
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "alice", Price: 100, Size: unit, ReduceOnly: true, Side: orderbook.Buy}); !errors.Is(err, ErrReduceOnly) {
+		t.Fatalf("Buying more of a long position should be rejected, got %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 5, UserID: "alice", Price: 110, Size: 15 * unit, ReduceOnly: true, Side: orderbook.Sell}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if o, _ := me.OrderBook.GetOrder(5); o.Size != 10*unit {
+		t.Fatalf("Expected order shrunk to the position, got %d", o.Size)
+	}
+}
+
+func TestReduceOnly_MakersCappedDuringMatching(t *testing.T) {
+	var reports []orderbook.ExecutionReport
+	me := NewMatchingEngine(WithExecutionReportHandler(func(r orderbook.ExecutionReport) { reports = append(reports, r) }))
+	fill(me, 1, "alice", "bob", 100, 5*unit)
+
+	// Both orders may close the whole position on their own
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 110, Size: 5 * unit, ReduceOnly: true, Side: orderbook.Sell, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "alice", Price: 110, Size: 5 * unit, ReduceOnly: true, Side: orderbook.Sell, Timestamp: 4})
+	me.PlaceOrder(&orderbook.Order{ID: 5, UserID: "carol", Price: 110, Size: 5 * unit, Side: orderbook.Sell, Timestamp: 5})
+
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 6, UserID: "dave", Price: 110, Size: 10 * unit, Side: orderbook.Buy, Timestamp: 6})
+	if len(events) != 2 || events[0].MakerOrderID != 3 || events[1].MakerOrderID != 5 || events[1].Size != 5*unit {
+		t.Fatalf("Expected fills against orders 3 and 5, got %v", events)
+	}
+	if p := me.Position("alice"); p.Size != 0 {
+		t.Fatalf("Reduce-only orders must not flip the position, got %+v", p)
+	}
+	if _, ok := me.OrderBook.GetOrder(4); ok {
+		t.Fatalf("Reduce-only order with nothing left to reduce should be cancelled")
+	}
+	if r := reports[len(reports)-1]; r.OrderID != 4 || r.Type != orderbook.ExecCancelled {
+		t.Errorf("Expected a cancel report for order 4, got %+v", r)
+	}
+}
+
+func TestReduceOnly_RestingResized(t *testing.T) {
+	me := NewMatchingEngine()
+	fill(me, 1, "alice", "bob", 100, 10*unit)
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "alice", Price: 120, Size: 10 * unit, ReduceOnly: true, Side: orderbook.Sell, Timestamp: 3})
+
+	// A plain sell reduces the position to 6
+	fill(me, 4, "carol", "alice", 100, 4*unit)
+	if o, _ := me.OrderBook.GetOrder(3); o.Size != 6*unit {
+		t.Fatalf("Expected resting reduce-only order resized to 6, got %d", o.Size)
+	}
+}
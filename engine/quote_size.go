@@ -0,0 +1,18 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// sizeQuoteOrder prepares a market buy sized by quote notional. Its Size is set to what the
+// quote buys at the best ask, an upper bound on the fill since later levels are more expensive;
+// matching then stops once the quote is spent.
+func (me *MatchingEngine) sizeQuoteOrder(order *orderbook.Order) error {
+	if order.QuoteSize < 0 || order.Type != orderbook.Market || order.Side != orderbook.Buy || order.Size != 0 {
+		return ErrInvalidQuoteOrder
+	}
+	ask := me.OrderBook.GetBestAsk()
+	if ask == nil {
+		return ErrNoLiquidity
+	}
+	order.Size = me.sizeForQuote(order.QuoteSize, ask.Price)
+	return nil
+}
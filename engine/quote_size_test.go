@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestQuoteSizedMarketBuy(t *testing.T) {
+	inst := DefaultInstrument()
+	inst.LotSize = 1e6 // 0.01 base
+	accounts := NewDefaultInMemoryAccountManager()
+	accounts.Deposit("buyer", "QUOTE", 1_000*1e8)
+	accounts.Deposit("seller", "BASE", 10*1e8)
+	me := NewMatchingEngine(WithInstrument(inst), WithAccountManager(accounts))
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "seller", Price: 100 * 1e8, Size: 2 * 1e8, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "seller", Price: 300 * 1e8, Size: 2 * 1e8, Side: orderbook.Sell, Timestamp: 1})
+
+	// $500: 2 BASE at 100 = $200, then $300 left buys 1 BASE at 300
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "buyer", Type: orderbook.Market, QuoteSize: 500 * 1e8, Side: orderbook.Buy, Timestamp: 2})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Size != 2*1e8 || events[1].Size != 1*1e8 {
+		t.Fatalf("Unexpected fills: %v", events)
+	}
+	if b := accounts.Balance("buyer", "QUOTE"); b.Available != 500*1e8 || b.Reserved != 0 {
+		t.Errorf("Buyer should have spent exactly $500, got %+v", b)
+	}
+
+	// $250 at 300 buys 0.8333 BASE, rounded down to the lot: 0.83 for $249
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "buyer", Type: orderbook.Market, QuoteSize: 250 * 1e8, Side: orderbook.Buy, Timestamp: 3})
+	if len(events) != 1 || events[0].Size != 83*1e6 {
+		t.Fatalf("Expected lot-rounded fill of 0.83, got %v", events)
+	}
+	if b := accounts.Balance("buyer", "QUOTE"); b.Available != 251*1e8 {
+		t.Errorf("Buyer should have spent $249, got %+v", b)
+	}
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 5, Type: orderbook.Limit, Price: 1, QuoteSize: 1, Side: orderbook.Buy}); !errors.Is(err, ErrInvalidQuoteOrder) {
+		t.Errorf("Expected ErrInvalidQuoteOrder, got %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 6, UserID: "seller", Price: 100 * 1e8, Size: 1e5, Side: orderbook.Sell}); !errors.Is(err, ErrInvalidLotSize) {
+		t.Errorf("Expected ErrInvalidLotSize, got %v", err)
+	}
+
+	// Pro-rata shares of 50 across makers of 30 and 40 are 21.4 and 28.6: rounded down to
+	// lots of 10 and the residual lot goes to the earlier maker
+	inst = DefaultInstrument()
+	inst.LotSize = 10
+	me = NewMatchingEngine(WithInstrument(inst), WithMatchingPolicy(ProRataPolicy{}))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 30, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 100, Size: 40, Side: orderbook.Sell, Timestamp: 2})
+	events, _ = me.PlaceOrder(&orderbook.Order{ID: 3, Price: 100, Size: 50, Side: orderbook.Buy, Timestamp: 3})
+	if len(events) != 2 || events[0].MakerOrderID != 1 || events[0].Size != 30 || events[1].MakerOrderID != 2 || events[1].Size != 20 {
+		t.Fatalf("Expected lot-rounded pro-rata fills of 30 and 20, got %v", events)
+	}
+}
+
+func TestQuoteSize_NoOverflow(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 1, Size: 5, Side: orderbook.Sell, Timestamp: 1})
+	// quote * scale overflows 64 bits; the 128-bit intermediate keeps sizing exact
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 2, Type: orderbook.Market, QuoteSize: 1 << 62, Side: orderbook.Buy, Timestamp: 2})
+	if err != nil || len(events) != 1 || events[0].Size != 5 {
+		t.Fatalf("Expected full fill of 5, got %v %v", events, err)
+	}
+}
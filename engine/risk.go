@@ -0,0 +1,153 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// BpsScale is the fixed-point scale of basis-point parameters (10_000 = 100%)
+const BpsScale int64 = 10_000
+
+// PriceReference selects the price that fat-finger bands are measured against
+type PriceReference int
+
+const (
+	// RefLastTradeOrMid uses the last trade price, falling back to the mid price
+	RefLastTradeOrMid PriceReference = iota
+	// RefLastTrade uses the last trade price only
+	RefLastTrade
+	// RefMid uses the mid price of the best bid and ask only
+	RefMid
+)
+
+// RiskConfig configures the pre-trade checks applied by PlaceOrder.
+// Zero values disable the corresponding check.
+type RiskConfig struct {
+	// MaxPriceDeviationBps rejects limit orders priced further than this from the reference price
+	MaxPriceDeviationBps int64
+	Reference            PriceReference
+	// MaxOrderSize rejects orders larger than this (fixed-point size)
+	MaxOrderSize int64
+	// MaxOrderNotional is the default per-order notional limit (fixed-point quote)
+	MaxOrderNotional int64
+	// UserMaxNotional overrides MaxOrderNotional per UserID
+	UserMaxNotional map[string]int64
+	// MarketProtectionBps caps how far a market order may walk the book
+	// away from the best opposite price at entry; the remainder is cancelled
+	MarketProtectionBps int64
+}
+
+// WithRiskConfig enables pre-trade risk checks
+func WithRiskConfig(cfg RiskConfig) Option {
+	return func(me *MatchingEngine) {
+		me.risk = &cfg
+	}
+}
+
+// LastTradePrice returns the price of the most recent fill, or 0 if nothing traded yet
+func (me *MatchingEngine) LastTradePrice() int64 {
+	return me.lastTradePrice
+}
+
+// midPrice returns the midpoint of the best bid and ask, or 0 if either side is empty
+func (me *MatchingEngine) midPrice() int64 {
+	bid := me.OrderBook.GetBestBid()
+	ask := me.OrderBook.GetBestAsk()
+	if bid == nil || ask == nil {
+		return 0
+	}
+	return bid.Price + (ask.Price-bid.Price)/2
+}
+
+// referencePrice returns the price bands are measured against, or 0 if unavailable
+func (me *MatchingEngine) referencePrice() int64 {
+	switch me.risk.Reference {
+	case RefLastTrade:
+		return me.lastTradePrice
+	case RefMid:
+		return me.midPrice()
+	default:
+		if me.lastTradePrice > 0 {
+			return me.lastTradePrice
+		}
+		return me.midPrice()
+	}
+}
+
+// bandPrice returns price moved by bps, upwards for Buy and downwards for Sell
+func bandPrice(price, bps int64, side orderbook.Side) int64 {
+	delta, _ := mulDiv(price, bps, BpsScale)
+	if side == orderbook.Buy {
+		return price + delta
+	}
+	return price - delta
+}
+
+// checkRisk applies the configured pre-trade checks to an incoming order
+func (me *MatchingEngine) checkRisk(order *orderbook.Order) error {
+	if me.risk == nil {
+		return nil
+	}
+	cfg := me.risk
+
+	if cfg.MaxOrderSize > 0 && order.Size > cfg.MaxOrderSize {
+		return ErrOrderSizeExceedsMax
+	}
+
+	if cfg.MaxPriceDeviationBps > 0 && order.Type == orderbook.Limit {
+		if ref := me.referencePrice(); ref > 0 {
+			if order.Price > bandPrice(ref, cfg.MaxPriceDeviationBps, orderbook.Buy) ||
+				order.Price < bandPrice(ref, cfg.MaxPriceDeviationBps, orderbook.Sell) {
+				return ErrPriceOutOfBand
+			}
+		}
+	}
+
+	limit := cfg.MaxOrderNotional
+	if l, ok := cfg.UserMaxNotional[order.UserID]; ok {
+		limit = l
+	}
+	if limit > 0 {
+		// Market orders are valued at the worst price they are allowed to reach
+		price := order.Price
+		if order.Type == orderbook.Market {
+			price, _ = me.marketProtectionPrice(order)
+			if price == 0 {
+				price = me.referencePrice()
+			}
+		}
+		if order.QuoteSize > 0 {
+			if order.QuoteSize > limit {
+				return ErrNotionalExceedsLimit
+			}
+		} else if price > 0 {
+			notional, ok := me.notional(price, order.Size)
+			if !ok || notional > limit {
+				return ErrNotionalExceedsLimit
+			}
+		}
+	}
+	return nil
+}
+
+// marketProtectionPrice returns the worst price a market order may trade at
+func (me *MatchingEngine) marketProtectionPrice(order *orderbook.Order) (int64, bool) {
+	if me.risk == nil || me.risk.MarketProtectionBps <= 0 {
+		return 0, false
+	}
+	var best *orderbook.Order
+	if order.Side == orderbook.Buy {
+		best = me.OrderBook.GetBestAsk()
+	} else {
+		best = me.OrderBook.GetBestBid()
+	}
+	if best == nil {
+		return 0, false
+	}
+	return bandPrice(best.Price, me.risk.MarketProtectionBps, order.Side), true
+}
+
+// priceLimit returns the worst price the order may match at and whether it is bounded
+func (me *MatchingEngine) priceLimit(order *orderbook.Order) (int64, bool) {
+	if order.Type == orderbook.Limit {
+		return order.Price, true
+	}
+	return me.marketProtectionPrice(order)
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+)
+
+func TestRisk_PriceBand(t *testing.T) {
+	me := NewMatchingEngine(WithRiskConfig(RiskConfig{MaxPriceDeviationBps: 1000})) // 10%
+
+	// No reference yet: anything goes
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 90, Size: 10, Side: orderbook.Buy, Timestamp: 1})
+
+	// Mid is 95: band is [85.5, 104.5]
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 3, Price: 105, Size: 1, Side: orderbook.Buy, Timestamp: 2}); !errors.Is(err, ErrPriceOutOfBand) {
+		t.Errorf("Expected ErrPriceOutOfBand, got %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, Price: 104, Size: 1, Side: orderbook.Sell, Timestamp: 2}); err != nil {
+		t.Errorf("Order inside band rejected: %v", err)
+	}
+
+	// Trade at 100 moves the reference to the last trade: band is [90, 110]
+	me.PlaceOrder(&orderbook.Order{ID: 5, Price: 100, Size: 1, Side: orderbook.Buy, Timestamp: 3})
+	if me.LastTradePrice() != 100 {
+		t.Fatalf("Expected last trade 100, got %d", me.LastTradePrice())
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 6, Price: 89, Size: 1, Side: orderbook.Sell, Timestamp: 4}); !errors.Is(err, ErrPriceOutOfBand) {
+		t.Errorf("Expected ErrPriceOutOfBand, got %v", err)
+	}
+}
+
+func TestRisk_SizeAndNotional(t *testing.T) {
+	me := NewMatchingEngine(WithRiskConfig(RiskConfig{
+		MaxOrderSize:     100,
+		MaxOrderNotional: 5000,
+		UserMaxNotional:  map[string]int64{"whale": 50000},
+	}), WithInstrument(Instrument{QuantityScale: 1}))
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, Price: 10, Size: 101, Side: orderbook.Buy, Timestamp: 1}); !errors.Is(err, ErrOrderSizeExceedsMax) {
+		t.Errorf("Expected ErrOrderSizeExceedsMax, got %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "minnow", Price: 100, Size: 51, Side: orderbook.Buy, Timestamp: 1}); !errors.Is(err, ErrNotionalExceedsLimit) {
+		t.Errorf("Expected ErrNotionalExceedsLimit, got %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "whale", Price: 100, Size: 51, Side: orderbook.Buy, Timestamp: 1}); err != nil {
+		t.Errorf("User override should allow order: %v", err)
+	}
+}
+
+func TestRisk_MarketProtection(t *testing.T) {
+	me := NewMatchingEngine(WithRiskConfig(RiskConfig{MarketProtectionBps: 500})) // 5%
+
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 105, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 3, Price: 106, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+
+	// Market buy may walk up to 105, the remainder is cancelled
+	events, err := me.PlaceOrder(&orderbook.Order{ID: 4, Type: orderbook.Market, Size: 30, Side: orderbook.Buy, Timestamp: 2})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(events) != 2 || events[1].Price != 105 {
+		t.Errorf("Expected fills at 100 and 105 only, got %v", events)
+	}
+	if best := me.OrderBook.GetBestAsk(); best == nil || best.ID != 3 {
+		t.Errorf("Order 3 should remain")
+	}
+	if _, ok := me.OrderBook.GetOrder(4); ok {
+		t.Errorf("Market order remainder should not rest")
+	}
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sort"
+	"time"
+)
+
+// SessionConfig configures cancel-on-disconnect behaviour
+type SessionConfig struct {
+	// HeartbeatTimeout marks a session disconnected when no heartbeat arrives for this long (0 disables)
+	HeartbeatTimeout time.Duration
+	// GracePeriod is how long a disconnected session's orders survive before being cancelled
+	GracePeriod time.Duration
+}
+
+// SessionState represents the liveness of a session
+type SessionState int
+
+const (
+	SessionActive SessionState = iota
+	SessionDisconnected
+)
+
+func (s SessionState) String() string {
+	if s == SessionActive {
+		return "Active"
+	}
+	return "Disconnected"
+}
+
+// Session links resting orders to a gateway connection
+type Session struct {
+	ID             string
+	UserID         string
+	State          SessionState
+	LastHeartbeat  int64 // Unix nanoseconds
+	DisconnectedAt int64 // Unix nanoseconds, 0 while active
+
+	orders map[uint64]struct{}
+}
+
+// WithSessions enables cancel-on-disconnect session handling
+func WithSessions(cfg SessionConfig) Option {
+	return func(me *MatchingEngine) {
+		me.sessionConfig = cfg
+	}
+}
+
+// OpenSession registers a new live session for the user
+func (me *MatchingEngine) OpenSession(sessionID, userID string) error {
+	if _, exists := me.sessions[sessionID]; exists {
+		return ErrSessionExists
+	}
+	me.sessions[sessionID] = &Session{
+		ID:            sessionID,
+		UserID:        userID,
+		State:         SessionActive,
+		LastHeartbeat: me.clock.Now(),
+		orders:        make(map[uint64]struct{}),
+	}
+	return nil
+}
+
+// Heartbeat refreshes session liveness. A disconnected session still within its grace period is revived;
+// one whose grace period has elapsed has its orders cancelled and is removed, as CheckSessions would have done.
+func (me *MatchingEngine) Heartbeat(sessionID string) error {
+	s, ok := me.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	now := me.clock.Now()
+	if me.sessionExpired(s, now) {
+		me.cancelOrders(s.orderIDs(), now)
+		delete(me.sessions, sessionID)
+		return ErrSessionExpired
+	}
+	s.LastHeartbeat = now
+	s.State = SessionActive
+	s.DisconnectedAt = 0
+	return nil
+}
+
+// Disconnect marks a session as dropped; its orders are cancelled once the grace period elapses
+func (me *MatchingEngine) Disconnect(sessionID string) error {
+	s, ok := me.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if s.State == SessionActive {
+		s.State = SessionDisconnected
+		s.DisconnectedAt = me.clock.Now()
+	}
+	return nil
+}
+
+// CloseSession cancels all orders of the session immediately and forgets it
+func (me *MatchingEngine) CloseSession(sessionID string) (*MassCancelResult, error) {
+	s, ok := me.sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	result := me.cancelOrders(s.orderIDs(), me.clock.Now())
+	delete(me.sessions, sessionID)
+	return result, nil
+}
+
+// GetSession returns a session by ID
+func (me *MatchingEngine) GetSession(sessionID string) (*Session, bool) {
+	s, ok := me.sessions[sessionID]
+	return s, ok
+}
+
+// CheckSessions evaluates heartbeat timeouts and grace periods against the engine clock,
+// cancelling all orders of sessions that have been disconnected for longer than the grace period.
+// Sessions are processed in ID order so replays produce identical output.
+func (me *MatchingEngine) CheckSessions() *MassCancelResult {
+	now := me.clock.Now()
+	ids := make([]string, 0, len(me.sessions))
+	for id := range me.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := &MassCancelResult{}
+	for _, id := range ids {
+		s := me.sessions[id]
+		if !me.sessionExpired(s, now) {
+			continue
+		}
+		cancelled := me.cancelOrders(s.orderIDs(), now)
+		result.Cancelled = append(result.Cancelled, cancelled.Cancelled...)
+		result.Levels = append(result.Levels, cancelled.Levels...)
+		delete(me.sessions, id)
+	}
+	return result
+}
+
+// sessionExpired marks a session whose heartbeat lapsed as disconnected and reports whether
+// it has been disconnected for at least the grace period
+func (me *MatchingEngine) sessionExpired(s *Session, now int64) bool {
+	cfg := me.sessionConfig
+	if s.State == SessionActive && cfg.HeartbeatTimeout > 0 &&
+		now-s.LastHeartbeat > int64(cfg.HeartbeatTimeout) {
+		s.State = SessionDisconnected
+		s.DisconnectedAt = s.LastHeartbeat + int64(cfg.HeartbeatTimeout)
+	}
+	return s.State == SessionDisconnected && now-s.DisconnectedAt >= int64(cfg.GracePeriod)
+}
+
+// orderIDs returns the session's resting order IDs in ascending order
+func (s *Session) orderIDs() []uint64 {
+	ids := make([]uint64, 0, len(s.orders))
+	for id := range s.orders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// checkSession rejects orders sent on an unknown or disconnected session, or on another user's session.
+// A session whose heartbeat lapsed by the order's timestamp counts as disconnected.
+func (me *MatchingEngine) checkSession(order *orderbook.Order) error {
+	if order.SessionID == "" {
+		return nil
+	}
+	s, ok := me.sessions[order.SessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if s.UserID != order.UserID {
+		return ErrSessionUserMismatch
+	}
+	me.sessionExpired(s, order.Timestamp)
+	if s.State != SessionActive {
+		return ErrSessionDisconnected
+	}
+	return nil
+}
+
+// linkSessionOrder records a resting order against its session
+func (me *MatchingEngine) linkSessionOrder(order *orderbook.Order) {
+	if s, ok := me.sessions[order.SessionID]; ok {
+		s.orders[order.ID] = struct{}{}
+	}
+}
+
+// unlinkSessionOrder forgets an order that left the book
+func (me *MatchingEngine) unlinkSessionOrder(order *orderbook.Order) {
+	if s, ok := me.sessions[order.SessionID]; ok {
+		delete(s.orders, order.ID)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+	"time"
+)
+
+func TestSessions_CancelOnDisconnect(t *testing.T) {
+	clock := NewManualClock(0)
+	me := NewMatchingEngine(WithClock(clock), WithSessions(SessionConfig{
+		HeartbeatTimeout: 5 * time.Second,
+		GracePeriod:      10 * time.Second,
+	}))
+
+	if err := me.OpenSession("s1", "mm"); err != nil {
+		t.Fatalf("OpenSession failed: %v", err)
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "mm", SessionID: "s1", Price: 99, Size: 10, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "mm", SessionID: "s1", Price: 101, Size: 10, Side: orderbook.Sell})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "mm", Price: 98, Size: 10, Side: orderbook.Buy}) // Not session-bound
+
+	// Heartbeats keep the session alive
+	clock.Advance(4 * time.Second)
+	me.Heartbeat("s1")
+	clock.Advance(4 * time.Second)
+	if res := me.CheckSessions(); len(res.Cancelled) != 0 {
+		t.Fatalf("Live session should keep its orders")
+	}
+
+	// Heartbeat lapses at t=9s, grace ends at t=19s
+	clock.Advance(6 * time.Second) // t=14s
+	if res := me.CheckSessions(); len(res.Cancelled) != 0 {
+		t.Fatalf("Orders should survive the grace period")
+	}
+	if s, _ := me.GetSession("s1"); s.State != SessionDisconnected {
+		t.Fatalf("Session should be disconnected")
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "mm", SessionID: "s1", Price: 99, Size: 1, Side: orderbook.Buy}); !errors.Is(err, ErrSessionDisconnected) {
+		t.Errorf("Expected ErrSessionDisconnected, got %v", err)
+	}
+
+	clock.Advance(5 * time.Second) // t=19s
+	res := me.CheckSessions()
+	if len(res.Cancelled) != 2 || res.Cancelled[0].ID != 1 || res.Cancelled[1].ID != 2 {
+		t.Fatalf("Expected orders 1 and 2 cancelled, got %v", res.Cancelled)
+	}
+	if _, ok := me.OrderBook.GetOrder(3); !ok {
+		t.Errorf("Order without session should remain")
+	}
+	if _, ok := me.GetSession("s1"); ok {
+		t.Errorf("Dead session should be removed")
+	}
+}
+
+func TestSessions_ReconnectWithinGrace(t *testing.T) {
+	clock := NewManualClock(0)
+	me := NewMatchingEngine(WithClock(clock), WithSessions(SessionConfig{GracePeriod: 10 * time.Second}))
+
+	me.OpenSession("s1", "mm")
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "mm", SessionID: "s1", Price: 99, Size: 10, Side: orderbook.Buy})
+	me.Disconnect("s1")
+
+	clock.Advance(9 * time.Second)
+	me.Heartbeat("s1")
+	clock.Advance(time.Minute)
+	if res := me.CheckSessions(); len(res.Cancelled) != 0 {
+		t.Errorf("Reconnected session should keep its orders")
+	}
+}
+
+func TestSessions_LateHeartbeatCancels(t *testing.T) {
+	clock := NewManualClock(0)
+	me := NewMatchingEngine(WithClock(clock), WithSessions(SessionConfig{GracePeriod: 10 * time.Second}))
+
+	me.OpenSession("s1", "mm")
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "mm", SessionID: "s1", Price: 99, Size: 10, Side: orderbook.Buy})
+	me.Disconnect("s1")
+
+	// No CheckSessions ran, but the grace period is over
+	clock.Advance(10 * time.Second)
+	if err := me.Heartbeat("s1"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Expected ErrSessionExpired, got %v", err)
+	}
+	if _, ok := me.OrderBook.GetOrder(1); ok {
+		t.Errorf("Orders of an expired session should be cancelled")
+	}
+	if _, ok := me.GetSession("s1"); ok {
+		t.Errorf("Expired session should be removed")
+	}
+}
+
+func TestSessions_OrderUserMustOwnSession(t *testing.T) {
+	me := NewMatchingEngine(WithSessions(SessionConfig{}))
+	me.OpenSession("s1", "mm")
+	_, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "other", SessionID: "s1", Price: 99, Size: 10, Side: orderbook.Buy})
+	if !errors.Is(err, ErrSessionUserMismatch) {
+		t.Fatalf("Expected ErrSessionUserMismatch, got %v", err)
+	}
+}
+
+func TestSessions_LapsedHeartbeatRejectsOrders(t *testing.T) {
+	clock := NewManualClock(0)
+	me := NewMatchingEngine(WithClock(clock), WithSessions(SessionConfig{
+		HeartbeatTimeout: 5 * time.Second,
+		GracePeriod:      10 * time.Second,
+	}))
+	me.OpenSession("s1", "mm")
+
+	// No CheckSessions ran, but the heartbeat lapsed at t=5s
+	clock.Advance(6 * time.Second)
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "mm", SessionID: "s1", Price: 99, Size: 1, Side: orderbook.Buy}); !errors.Is(err, ErrSessionDisconnected) {
+		t.Fatalf("Expected ErrSessionDisconnected, got %v", err)
+	}
+	if s, _ := me.GetSession("s1"); s.State != SessionDisconnected {
+		t.Errorf("Session should be marked disconnected")
+	}
+}
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"sort"
+)
+
+// stopBook holds Stop/StopLimit orders waiting for their trigger.
+// Buy stops trigger when the last trade price rises to their stop price, sell stops when it falls to it.
+type stopBook struct {
+	buys     []*orderbook.Order // Ascending stop price, then arrival
+	sells    []*orderbook.Order // Descending stop price, then arrival
+	trailing []*trailingStop    // Trailing stops in arrival order
+	orders   map[uint64]*orderbook.Order
+}
+
+func newStopBook() stopBook {
+	return stopBook{orders: make(map[uint64]*orderbook.Order)}
+}
+
+// add parks an order. Trailing stops are anchored on the last trade price.
+func (b *stopBook) add(order *orderbook.Order, last int64) {
+	b.orders[order.ID] = order
+	if order.Type.IsTrailing() {
+		ts := &trailingStop{order: order}
+		b.trailing = append(b.trailing, ts)
+		b.trail(ts, last)
+		return
+	}
+	b.insert(order)
+}
+
+// insert queues an order behind every stop with the same or a closer trigger
+func (b *stopBook) insert(order *orderbook.Order) {
+	if order.Side == orderbook.Buy {
+		i := sort.Search(len(b.buys), func(i int) bool { return b.buys[i].StopPrice > order.StopPrice })
+		b.buys = insertOrder(b.buys, i, order)
+	} else {
+		i := sort.Search(len(b.sells), func(i int) bool { return b.sells[i].StopPrice < order.StopPrice })
+		b.sells = insertOrder(b.sells, i, order)
+	}
+}
+
+// remove takes a parked order out of the book
+func (b *stopBook) remove(orderID uint64) (*orderbook.Order, bool) {
+	order, ok := b.orders[orderID]
+	if !ok {
+		return nil, false
+	}
+	delete(b.orders, orderID)
+	if order.Side == orderbook.Buy {
+		b.buys = deleteOrder(b.buys, orderID)
+	} else {
+		b.sells = deleteOrder(b.sells, orderID)
+	}
+	if order.Type.IsTrailing() {
+		for i, ts := range b.trailing {
+			if ts.order.ID == orderID {
+				b.trailing = append(b.trailing[:i], b.trailing[i+1:]...)
+				break
+			}
+		}
+	}
+	return order, true
+}
+
+// next returns the first stop triggered by the last trade price, buys first
+func (b *stopBook) next(last int64) *orderbook.Order {
+	if last <= 0 {
+		return nil
+	}
+	if len(b.buys) > 0 && b.buys[0].StopPrice <= last {
+		return b.buys[0]
+	}
+	if len(b.sells) > 0 && b.sells[0].StopPrice >= last {
+		return b.sells[0]
+	}
+	return nil
+}
+
+func insertOrder(s []*orderbook.Order, i int, o *orderbook.Order) []*orderbook.Order {
+	s = append(s, nil)
+	copy(s[i+1:], s[i:])
+	s[i] = o
+	return s
+}
+
+func deleteOrder(s []*orderbook.Order, orderID uint64) []*orderbook.Order {
+	for i, o := range s {
+		if o.ID == orderID {
+			copy(s[i:], s[i+1:])
+			s[len(s)-1] = nil
+			return s[:len(s)-1]
+		}
+	}
+	return s
+}
+
+// GetStopOrder returns a stop order that has not triggered yet
+func (me *MatchingEngine) GetStopOrder(orderID uint64) (*orderbook.Order, bool) {
+	order, ok := me.stops.orders[orderID]
+	return order, ok
+}
+
+// parkStop stores a stop order until the market reaches its trigger
+func (me *MatchingEngine) parkStop(order *orderbook.Order) {
+	me.stops.add(order, me.lastTradePrice)
+	me.linkSessionOrder(order)
+	me.indexExpiry(order)
+}
+
+// cancelStop removes a parked stop order
+func (me *MatchingEngine) cancelStop(orderID uint64) error {
+	order, ok := me.stops.remove(orderID)
+	if !ok {
+		return ErrOrderNotFound
+	}
+	me.finishOrder(order)
+	return nil
+}
+
+// processContingent runs the orders that became due while processing a command: exits of
+// filled bracket entries and stops triggered by the last trade price, then re-prices pegged
+// orders to the resulting book, crosses the dark book at the new midpoint and resizes
+// reduce-only orders to the new positions. Triggered orders are processed one at a time in
+// trigger order, so the outcome depends only on the command stream. The resulting fills are
+// appended to events.
+func (me *MatchingEngine) processContingent(events []orderbook.MatchEvent, now int64) []orderbook.MatchEvent {
+	for me.state.acceptsOrders() {
+		if len(me.pendingBrackets) > 0 {
+			g := me.pendingBrackets[0]
+			me.pendingBrackets = me.pendingBrackets[1:]
+			events = append(events, me.activateBracket(g, now)...)
+			continue
+		}
+		if me.state != TradingContinuous {
+			break
+		}
+		stop := me.stops.next(me.lastTradePrice)
+		if stop == nil {
+			break
+		}
+		events = append(events, me.triggerStop(stop, now)...)
+	}
+	if me.state == TradingContinuous && len(me.pegs) > 0 {
+		me.repeg()
+	}
+	if me.state == TradingContinuous && len(me.dark.orders) > 0 {
+		events = append(events, me.crossDark(now)...)
+	}
+	me.resizeReduceOnly(now)
+	return events
+}
+
+// triggerStop converts a triggered stop into a market or limit order and submits it
+func (me *MatchingEngine) triggerStop(order *orderbook.Order, now int64) []orderbook.MatchEvent {
+	me.stops.remove(order.ID)
+	me.unlinkSessionOrder(order)
+	switch order.Type {
+	case orderbook.Stop, orderbook.TrailingStop:
+		order.Type = orderbook.Market
+	case orderbook.TrailingStopLimit:
+		order.Type = orderbook.Limit
+		order.Price = order.StopPrice
+	default:
+		order.Type = orderbook.Limit
+	}
+	order.Timestamp = now
+	me.emitReports([]orderbook.ExecutionReport{execReport(order, orderbook.ExecTriggered, now)})
+	me.onTrigger(order, now)
+	return me.submitEngineOrder(order, now)
+}
+
+// submitEngineOrder submits an order generated by the engine (triggered stop, activated exit),
+// reporting it as rejected if it fails admission
+func (me *MatchingEngine) submitEngineOrder(order *orderbook.Order, now int64) []orderbook.MatchEvent {
+	events, err := me.submitOrder(order)
+	if err != nil {
+		me.emitReports([]orderbook.ExecutionReport{execReport(order, orderbook.ExecRejected, now)})
+		me.finishOrder(order)
+		return nil
+	}
+	return events
+}
+
+// execReport builds an execution report for the current state of an order
+func execReport(order *orderbook.Order, typ orderbook.ExecType, now int64) orderbook.ExecutionReport {
+	return orderbook.ExecutionReport{
+		OrderID:    order.ID,
+		UserID:     order.UserID,
+		Type:       typ,
+		Side:       order.Side,
+		Price:      order.Price,
+		LeavesSize: order.Size,
+		Timestamp:  now,
+	}
+}
+
+// validateStop checks the trigger parameters of stop orders
+func validateStop(order *orderbook.Order) error {
+	switch {
+	case order.Type.IsTrailing():
+		if (order.TrailAmount > 0) == (order.TrailBps > 0) || order.TrailAmount < 0 || order.TrailBps < 0 {
+			return ErrInvalidTrail
+		}
+	case order.Type.IsStop():
+		if order.StopPrice <= 0 {
+			return ErrInvalidStopPrice
+		}
+	}
+	return nil
+}
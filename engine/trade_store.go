@@ -0,0 +1,310 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"orderbook-matching-engine/orderbook"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DefaultTradePageSize is the page size of trade queries that do not set a limit
+const DefaultTradePageSize = 100
+
+// TradeQuery selects stored trades. Filters combine; zero values match everything.
+type TradeQuery struct {
+	OrderID uint64 // Trades where the order was maker or taker
+	UserID  string // Trades where the user was maker or taker
+	From    int64  // Earliest timestamp, inclusive
+	To      int64  // Latest timestamp, inclusive; 0 = no bound
+	After   uint64 // Cursor: only trades with a greater TradeID
+	Limit   int    // Page size, DefaultTradePageSize if 0
+}
+
+// TradePage is one page of a trade query, in TradeID order
+type TradePage struct {
+	Trades []orderbook.MatchEvent `json:"trades"`
+	Next   uint64                 `json:"next,omitempty"` // Cursor for the next page, 0 on the last page
+}
+
+// TradeStore defines the interface for the trade history fed with every fill
+type TradeStore interface {
+	// Append stores a trade; trades arrive in TradeID order
+	Append(trade orderbook.MatchEvent) error
+	// Get returns a trade by ID
+	Get(tradeID uint64) (orderbook.MatchEvent, bool)
+	// Query returns a page of matching trades
+	Query(q TradeQuery) TradePage
+	// LastTradeID returns the highest stored TradeID, 0 if empty
+	LastTradeID() uint64
+}
+
+// inMemoryTradeStore is a thread-safe in-memory TradeStore. Time bounds are found by binary
+// search while timestamps never decrease in TradeID order, which engine clocks guarantee.
+// Otherwise time-only queries sort their window of the time index by TradeID and order or
+// user queries scan the trades of that order or user.
+type inMemoryTradeStore struct {
+	mu      sync.RWMutex
+	trades  []orderbook.MatchEvent // TradeID order
+	byID    map[uint64]int
+	byOrder map[uint64][]int
+	byUser  map[string][]int
+	byTime  []int // Indexes into trades in timestamp order, ties in TradeID order
+	ordered bool  // Timestamps never decreased: trades is in timestamp order too
+}
+
+// NewInMemoryTradeStore provides a thread-safe in-memory implementation
+func NewInMemoryTradeStore() TradeStore {
+	return newInMemoryTradeStore()
+}
+
+func newInMemoryTradeStore() *inMemoryTradeStore {
+	return &inMemoryTradeStore{
+		byID:    make(map[uint64]int),
+		byOrder: make(map[uint64][]int),
+		byUser:  make(map[string][]int),
+		ordered: true,
+	}
+}
+
+func (s *inMemoryTradeStore) Append(trade orderbook.MatchEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := len(s.trades)
+	s.trades = append(s.trades, trade)
+	s.byID[trade.TradeID] = idx
+	s.byOrder[trade.MakerOrderID] = append(s.byOrder[trade.MakerOrderID], idx)
+	if trade.TakerOrderID != trade.MakerOrderID {
+		s.byOrder[trade.TakerOrderID] = append(s.byOrder[trade.TakerOrderID], idx)
+	}
+	if trade.MakerUserID != "" {
+		s.byUser[trade.MakerUserID] = append(s.byUser[trade.MakerUserID], idx)
+	}
+	if trade.TakerUserID != "" && trade.TakerUserID != trade.MakerUserID {
+		s.byUser[trade.TakerUserID] = append(s.byUser[trade.TakerUserID], idx)
+	}
+	pos := sort.Search(len(s.byTime), func(i int) bool { return s.trades[s.byTime[i]].Timestamp > trade.Timestamp })
+	if pos < len(s.byTime) {
+		s.ordered = false
+	}
+	s.byTime = append(s.byTime, 0)
+	copy(s.byTime[pos+1:], s.byTime[pos:])
+	s.byTime[pos] = idx
+	return nil
+}
+
+func (s *inMemoryTradeStore) Get(tradeID uint64) (orderbook.MatchEvent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, ok := s.byID[tradeID]
+	if !ok {
+		return orderbook.MatchEvent{}, false
+	}
+	return s.trades[i], true
+}
+
+func (s *inMemoryTradeStore) LastTradeID() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.trades) == 0 {
+		return 0
+	}
+	return s.trades[len(s.trades)-1].TradeID
+}
+
+func (s *inMemoryTradeStore) Query(q TradeQuery) TradePage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultTradePageSize
+	}
+
+	// Candidates from the most selective index, all in TradeID order
+	indexed := q.OrderID != 0 || q.UserID != ""
+	timed := q.From != 0 || q.To != 0
+	candidates := s.byOrder[q.OrderID]
+	if q.OrderID == 0 {
+		candidates = s.byUser[q.UserID]
+	}
+	if timed && !indexed && !s.ordered {
+		candidates = s.timeWindow(q.From, q.To)
+		indexed = true
+	}
+	n := len(s.trades)
+	if indexed {
+		n = len(candidates)
+	}
+	at := func(i int) *orderbook.MatchEvent {
+		if indexed {
+			return &s.trades[candidates[i]]
+		}
+		return &s.trades[i]
+	}
+
+	// Candidates in timestamp order are bounded by binary search
+	end := n
+	start := sort.Search(n, func(i int) bool { return at(i).TradeID > q.After })
+	if timed && s.ordered {
+		start = max(start, sort.Search(n, func(i int) bool { return at(i).Timestamp >= q.From }))
+		if q.To != 0 {
+			end = sort.Search(n, func(i int) bool { return at(i).Timestamp > q.To })
+		}
+	}
+
+	var page TradePage
+	for i := start; i < end; i++ {
+		t := at(i)
+		if !q.matches(t) {
+			continue
+		}
+		if len(page.Trades) == limit {
+			page.Next = page.Trades[limit-1].TradeID
+			break
+		}
+		page.Trades = append(page.Trades, *t)
+	}
+	return page
+}
+
+// timeWindow returns the indexes of the trades with from <= Timestamp <= to (0 = no bound) in TradeID order
+func (s *inMemoryTradeStore) timeWindow(from, to int64) []int {
+	lo := sort.Search(len(s.byTime), func(i int) bool { return s.trades[s.byTime[i]].Timestamp >= from })
+	hi := len(s.byTime)
+	if to != 0 {
+		hi = sort.Search(len(s.byTime), func(i int) bool { return s.trades[s.byTime[i]].Timestamp > to })
+	}
+	window := append([]int(nil), s.byTime[lo:max(lo, hi)]...)
+	sort.Ints(window)
+	return window
+}
+
+// matches applies the filters of a query to a trade
+func (q *TradeQuery) matches(t *orderbook.MatchEvent) bool {
+	if q.OrderID != 0 && t.MakerOrderID != q.OrderID && t.TakerOrderID != q.OrderID {
+		return false
+	}
+	if q.UserID != "" && t.MakerUserID != q.UserID && t.TakerUserID != q.UserID {
+		return false
+	}
+	if t.Timestamp < q.From || (q.To != 0 && t.Timestamp > q.To) {
+		return false
+	}
+	return true
+}
+
+// FileTradeStore is an in-memory TradeStore that also appends every trade to a file as a
+// JSON line. Opening an existing file replays it.
+type FileTradeStore struct {
+	*inMemoryTradeStore
+	file *os.File
+}
+
+// OpenFileTradeStore opens or creates an append-only trade file and loads the trades it holds.
+// A last line cut short by a crash during Append is truncated; any other bad line is an error.
+func OpenFileTradeStore(path string) (*FileTradeStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileTradeStore{inMemoryTradeStore: newInMemoryTradeStore(), file: f}
+	r := bufio.NewReader(f)
+	good := int64(0) // Offset after the last complete line
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				err = f.Truncate(good)
+			} else {
+				err = nil
+			}
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			return s, nil
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		var trade orderbook.MatchEvent
+		if err := json.Unmarshal(line, &trade); err != nil {
+			f.Close()
+			return nil, err
+		}
+		s.inMemoryTradeStore.Append(trade)
+		good += int64(len(line))
+	}
+}
+
+// Append writes the trade to the file, then indexes it. Each trade is written in a single
+// write call, so it reaches the operating system before Append returns.
+func (s *FileTradeStore) Append(trade orderbook.MatchEvent) error {
+	line, err := json.Marshal(trade)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := s.file.Write(line); err != nil {
+		return err
+	}
+	return s.inMemoryTradeStore.Append(trade)
+}
+
+// Sync commits written trades to stable storage
+func (s *FileTradeStore) Sync() error {
+	return s.file.Sync()
+}
+
+// Close closes the file
+func (s *FileTradeStore) Close() error {
+	return s.file.Close()
+}
+
+// WithTradeStore records every fill in a trade store. Trade IDs continue after the last
+// trade already stored.
+func WithTradeStore(store TradeStore) Option {
+	return func(me *MatchingEngine) {
+		me.trades = store
+		me.tradeSeq = store.LastTradeID()
+	}
+}
+
+// Trades returns the attached trade store, or nil
+func (me *MatchingEngine) Trades() TradeStore {
+	return me.trades
+}
+
+// newMatchEvent builds the fill of a maker and a taker and assigns it the next trade ID
+func (me *MatchingEngine) newMatchEvent(maker, taker *orderbook.Order, price, size, now int64) orderbook.MatchEvent {
+	me.tradeSeq++
+	return orderbook.MatchEvent{
+		TradeID:      me.tradeSeq,
+		MakerOrderID: maker.ID,
+		TakerOrderID: taker.ID,
+		MakerUserID:  maker.UserID,
+		TakerUserID:  taker.UserID,
+		Price:        price,
+		Size:         size,
+		Timestamp:    now,
+	}
+}
+
+// TradeStoreErr returns the first error the trade store failed a fill with, nil if every fill was stored
+func (me *MatchingEngine) TradeStoreErr() error {
+	return me.tradeStoreErr
+}
+
+// recordTrade stores a completed fill. A store that fails to persist it keeps matching
+// unaffected; the first failure is kept for TradeStoreErr.
+func (me *MatchingEngine) recordTrade(ev *orderbook.MatchEvent) {
+	if me.trades == nil {
+		return
+	}
+	if err := me.trades.Append(*ev); err != nil && me.tradeStoreErr == nil {
+		me.tradeStoreErr = err
+	}
+}
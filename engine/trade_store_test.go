@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func placeTrades(me *MatchingEngine) {
+	me.PlaceOrder(&orderbook.Order{ID: 1, UserID: "alice", Price: 100, Size: 10, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, UserID: "bob", Price: 100, Size: 4, Side: orderbook.Buy, Timestamp: 2})
+	me.PlaceOrder(&orderbook.Order{ID: 3, UserID: "carol", Price: 100, Size: 3, Side: orderbook.Buy, Timestamp: 3})
+	me.PlaceOrder(&orderbook.Order{ID: 4, UserID: "bob", Price: 100, Size: 3, Side: orderbook.Buy, Timestamp: 4})
+}
+
+func TestTradeStore_Queries(t *testing.T) {
+	store := NewInMemoryTradeStore()
+	me := NewMatchingEngine(WithTradeStore(store))
+	placeTrades(me)
+
+	if tr, ok := store.Get(2); !ok || tr.TakerOrderID != 3 || tr.TakerUserID != "carol" || tr.MakerUserID != "alice" {
+		t.Fatalf("Unexpected trade 2: %+v", tr)
+	}
+
+	// Fills for order 1, two per page
+	page := store.Query(TradeQuery{OrderID: 1, Limit: 2})
+	if len(page.Trades) != 2 || page.Trades[0].TradeID != 1 || page.Next != 2 {
+		t.Fatalf("Unexpected first page %+v", page)
+	}
+	page = store.Query(TradeQuery{OrderID: 1, Limit: 2, After: page.Next})
+	if len(page.Trades) != 1 || page.Trades[0].TradeID != 3 || page.Next != 0 {
+		t.Fatalf("Unexpected last page %+v", page)
+	}
+
+	page = store.Query(TradeQuery{UserID: "bob", From: 3, To: 10})
+	if len(page.Trades) != 1 || page.Trades[0].TradeID != 3 {
+		t.Fatalf("Expected bob's trade at time 4 only, got %+v", page)
+	}
+	if page := store.Query(TradeQuery{To: 3}); len(page.Trades) != 2 {
+		t.Errorf("Expected two trades up to time 3, got %+v", page)
+	}
+}
+
+func TestTradeStore_FileReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trades.jsonl")
+	store, err := OpenFileTradeStore(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	placeTrades(NewMatchingEngine(WithTradeStore(store)))
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = OpenFileTradeStore(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer store.Close()
+	if store.LastTradeID() != 3 {
+		t.Fatalf("Expected 3 trades replayed, got last ID %d", store.LastTradeID())
+	}
+	if page := store.Query(TradeQuery{UserID: "carol"}); len(page.Trades) != 1 || page.Trades[0].TradeID != 2 {
+		t.Fatalf("Replayed trades not indexed: %+v", page)
+	}
+
+	// A new engine continues the trade ID sequence
+	me := NewMatchingEngine(WithTradeStore(store))
+	me.PlaceOrder(&orderbook.Order{ID: 5, UserID: "dave", Price: 100, Size: 1, Side: orderbook.Sell, Timestamp: 5})
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: 6, UserID: "erin", Price: 100, Size: 1, Side: orderbook.Buy, Timestamp: 6})
+	if len(events) != 1 || events[0].TradeID != 4 {
+		t.Fatalf("Expected trade ID 4, got %v", events)
+	}
+}
+
+func TestTradeStore_TimeIndex(t *testing.T) {
+	store := NewInMemoryTradeStore()
+	for i, ts := range []int64{10, 20, 20, 30, 40} {
+		store.Append(orderbook.MatchEvent{TradeID: uint64(i + 1), MakerUserID: "alice", Timestamp: ts})
+	}
+	page := store.Query(TradeQuery{From: 20, To: 30, Limit: 2})
+	if len(page.Trades) != 2 || page.Trades[0].TradeID != 2 || page.Next != 3 {
+		t.Fatalf("Unexpected first page %+v", page)
+	}
+	page = store.Query(TradeQuery{From: 20, To: 30, Limit: 2, After: page.Next})
+	if len(page.Trades) != 1 || page.Trades[0].TradeID != 4 || page.Next != 0 {
+		t.Fatalf("Unexpected last page %+v", page)
+	}
+
+	// A trade stamped earlier than its predecessors is still found, in TradeID order
+	store.Append(orderbook.MatchEvent{TradeID: 6, MakerUserID: "alice", Timestamp: 15})
+	page = store.Query(TradeQuery{From: 15, To: 20})
+	if len(page.Trades) != 3 || page.Trades[0].TradeID != 2 || page.Trades[2].TradeID != 6 {
+		t.Fatalf("Expected trades 2, 3 and 6, got %+v", page)
+	}
+	if page := store.Query(TradeQuery{UserID: "alice", From: 15, To: 15}); len(page.Trades) != 1 || page.Trades[0].TradeID != 6 {
+		t.Fatalf("Expected trade 6 for alice, got %+v", page)
+	}
+}
+
+func TestTradeStore_FileDurability(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trades.jsonl")
+	store, err := OpenFileTradeStore(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	me := NewMatchingEngine(WithTradeStore(store))
+	placeTrades(me)
+
+	// Appends reach the file without Close
+	reader, err := OpenFileTradeStore(path)
+	if err != nil || reader.LastTradeID() != 3 {
+		t.Fatalf("Expected 3 trades visible before Close, got %v %v", reader, err)
+	}
+	reader.Close()
+
+	// A crash mid-append leaves a torn line, dropped on reopen
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"trade_id":4,"pri`)
+	f.Close()
+	store.Close()
+	store, err = OpenFileTradeStore(path)
+	if err != nil || store.LastTradeID() != 3 {
+		t.Fatalf("Expected the torn line dropped, got %v %v", store, err)
+	}
+	if err := store.Append(orderbook.MatchEvent{TradeID: 4, Timestamp: 5}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	store.Close()
+	if store, err = OpenFileTradeStore(path); err != nil || store.LastTradeID() != 4 {
+		t.Fatalf("Expected 4 trades after truncation, got %v %v", store, err)
+	}
+	store.Close()
+
+	// Store failures are surfaced by the engine
+	placeTrades(me)
+	if me.TradeStoreErr() == nil {
+		t.Errorf("Expected the closed store's error to be kept")
+	}
+}
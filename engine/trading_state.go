@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"orderbook-matching-engine/orderbook"
+	"sort"
+	"strings"
+)
+
+// TradingState represents the trading phase of the book
+//
+//	State        Place            Cancel  Matching
+//	PreOpen      no               yes     no
+//	Auction      limit only       yes     uncross on exit to Continuous/Closed
+//	Continuous   yes              yes     continuous
+//	Halted       no               no      no
+//	CancelOnly   no               yes     no
+//	Closed       no               no      no
+type TradingState int
+
+const (
+	TradingContinuous TradingState = iota
+	TradingPreOpen
+	TradingAuction
+	TradingHalted
+	TradingCancelOnly
+	TradingClosed
+)
+
+var tradingStateNames = map[TradingState]string{
+	TradingContinuous: "Continuous",
+	TradingPreOpen:    "PreOpen",
+	TradingAuction:    "Auction",
+	TradingHalted:     "Halted",
+	TradingCancelOnly: "CancelOnly",
+	TradingClosed:     "Closed",
+}
+
+func (s TradingState) String() string {
+	if name, ok := tradingStateNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+func (s TradingState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *TradingState) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	for state, name := range tradingStateNames {
+		if strings.EqualFold(name, str) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid trading state: %s", str)
+}
+
+// acceptsOrders reports whether new orders may be placed in this state
+func (s TradingState) acceptsOrders() bool {
+	return s == TradingContinuous || s == TradingAuction
+}
+
+// acceptsCancels reports whether orders may be cancelled in this state
+func (s TradingState) acceptsCancels() bool {
+	return s != TradingHalted && s != TradingClosed
+}
+
+// ScheduledTransition is an entry of the trading calendar
+type ScheduledTransition struct {
+	At    int64        `json:"at"` // Unix nanoseconds
+	State TradingState `json:"state"`
+}
+
+// TradingStateChange is published on the market data feed whenever the trading state changes
+type TradingStateChange struct {
+	From   TradingState `json:"from"`
+	To     TradingState `json:"to"`
+	Reason string       `json:"reason"`
+}
+
+// WithTradingSchedule sets the calendar of scheduled state transitions
+func WithTradingSchedule(schedule []ScheduledTransition) Option {
+	return func(me *MatchingEngine) {
+		me.schedule = append([]ScheduledTransition(nil), schedule...)
+		sort.SliceStable(me.schedule, func(i, j int) bool { return me.schedule[i].At < me.schedule[j].At })
+	}
+}
+
+// WithInitialTradingState sets the state the engine starts in (Continuous by default)
+func WithInitialTradingState(state TradingState) Option {
+	return func(me *MatchingEngine) {
+		me.state = state
+	}
+}
+
+// TradingState returns the current trading state
+func (me *MatchingEngine) TradingState() TradingState {
+	return me.state
+}
+
+// SetTradingState is an administrative override of the trading state.
+// Leaving an auction for Continuous or Closed, or entering Continuous with a crossed book,
+// uncrosses the book and returns the fills, including those of stops the uncross triggered.
+func (me *MatchingEngine) SetTradingState(state TradingState) ([]orderbook.MatchEvent, error) {
+	now := me.clock.Now()
+	events, err := me.transition(state, "admin", now)
+	if err != nil {
+		return nil, err
+	}
+	return me.processContingent(events, now), nil
+}
+
+// ProcessSchedule applies every scheduled transition that is due at the engine clock,
+// including the end of a volatility auction, and returns the fills of any auction uncrossed on the way
+func (me *MatchingEngine) ProcessSchedule() ([]orderbook.MatchEvent, error) {
+	return me.processSchedule(me.clock.Now())
+}
+
+// processSchedule applies every transition due at now
+func (me *MatchingEngine) processSchedule(now int64) ([]orderbook.MatchEvent, error) {
+	var events []orderbook.MatchEvent
+	if me.volatilityAuctionEnd > 0 && me.volatilityAuctionEnd <= now && me.state == TradingAuction {
+		fills, err := me.transition(TradingContinuous, "volatility", now)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, fills...)
+	}
+	for len(me.schedule) > 0 && me.schedule[0].At <= now {
+		next := me.schedule[0]
+		me.schedule = me.schedule[1:]
+		if next.State == me.state {
+			continue
+		}
+		fills, err := me.transition(next.State, "schedule", now)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, fills...)
+	}
+	return me.processContingent(events, now), nil
+}
+
+// transition moves the book to a new state at now, uncrossing a running auction if required
+func (me *MatchingEngine) transition(state TradingState, reason string, now int64) ([]orderbook.MatchEvent, error) {
+	if _, ok := tradingStateNames[state]; !ok {
+		return nil, ErrInvalidTradingState
+	}
+	if state == me.state {
+		return nil, ErrInvalidTradingState
+	}
+	from := me.state
+	me.state = state
+	me.publish(MarketDataEvent{
+		Type:         MDTradingState,
+		Timestamp:    now,
+		TradingState: &TradingStateChange{From: from, To: state, Reason: reason},
+	})
+
+	var events []orderbook.MatchEvent
+	uncross := from == TradingAuction && (state == TradingContinuous || state == TradingClosed)
+	if from == TradingAuction {
+		me.volatilityAuctionEnd = 0
+	}
+	// An auction left for a non-matching state keeps its crossed book until trading reopens
+	if state == TradingContinuous && me.bookCrossed() {
+		uncross = true
+	}
+	if uncross {
+		ind := me.IndicativeUncross()
+		events = me.uncross(ind, now)
+		if ind.Price > 0 {
+			// Auction prints re-anchor the static volatility band
+			me.staticReference = ind.Price
+		}
+	}
+	if state == TradingAuction {
+		me.publishIndicative()
+	}
+	return events, nil
+}
+
+// bookCrossed reports whether the best bid is at or above the best ask
+func (me *MatchingEngine) bookCrossed() bool {
+	bid, ask := me.OrderBook.GetBestBid(), me.OrderBook.GetBestAsk()
+	return bid != nil && ask != nil && bid.Price >= ask.Price
+}
+
+// checkTradingState enforces the state's order entry rules
+func (me *MatchingEngine) checkTradingState(order *orderbook.Order) error {
+	if !me.state.acceptsOrders() {
+		return ErrPlaceNotAllowed
+	}
+	if order.Type == orderbook.Market && me.state == TradingAuction {
+		return ErrMarketOrderInAuction
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"errors"
+	"orderbook-matching-engine/orderbook"
+	"testing"
+	"time"
+)
+
+func TestTradingState_ScheduleAndOverrides(t *testing.T) {
+	clock := NewManualClock(0)
+	feed := NewDefaultInMemoryMarketDataFeed()
+	hour := int64(time.Hour)
+	me := NewMatchingEngine(
+		WithClock(clock),
+		WithMarketDataPublisher(feed),
+		WithInitialTradingState(TradingPreOpen),
+		WithTradingSchedule([]ScheduledTransition{
+			{At: 9 * hour, State: TradingContinuous},
+			{At: 8 * hour, State: TradingAuction},
+			{At: 16 * hour, State: TradingClosed},
+		}),
+	)
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Buy}); !errors.Is(err, ErrPlaceNotAllowed) {
+		t.Errorf("Expected ErrPlaceNotAllowed in PreOpen, got %v", err)
+	}
+
+	// Opening auction
+	clock.Set(8 * hour)
+	me.ProcessSchedule()
+	if me.TradingState() != TradingAuction {
+		t.Fatalf("Expected Auction, got %v", me.TradingState())
+	}
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 10, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 100, Size: 4, Side: orderbook.Sell})
+
+	// Open: the auction uncrosses
+	clock.Set(9 * hour)
+	events, err := me.ProcessSchedule()
+	if err != nil || len(events) != 1 || events[0].Size != 4 {
+		t.Fatalf("Expected opening uncross of 4, got %v (%v)", events, err)
+	}
+
+	// Administrative halt blocks both order entry and cancels
+	if _, err := me.SetTradingState(TradingHalted); err != nil {
+		t.Fatalf("SetTradingState failed: %v", err)
+	}
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 3, Price: 100, Size: 1, Side: orderbook.Sell}); !errors.Is(err, ErrPlaceNotAllowed) {
+		t.Errorf("Expected ErrPlaceNotAllowed while halted, got %v", err)
+	}
+	if err := me.CancelOrder(1); !errors.Is(err, ErrCancelNotAllowed) {
+		t.Errorf("Expected ErrCancelNotAllowed while halted, got %v", err)
+	}
+
+	// Cancel-only lets participants pull orders
+	me.SetTradingState(TradingCancelOnly)
+	if err := me.CancelOrder(1); err != nil {
+		t.Errorf("Cancel should be allowed in CancelOnly: %v", err)
+	}
+	if _, err := me.SetTradingState(TradingCancelOnly); !errors.Is(err, ErrInvalidTradingState) {
+		t.Errorf("Expected ErrInvalidTradingState for no-op transition, got %v", err)
+	}
+
+	clock.Set(16 * hour)
+	me.ProcessSchedule()
+	if me.TradingState() != TradingClosed {
+		t.Errorf("Expected Closed, got %v", me.TradingState())
+	}
+
+	var changes []TradingStateChange
+	for _, ev := range feed.Drain() {
+		if ev.Type == MDTradingState {
+			changes = append(changes, *ev.TradingState)
+		}
+	}
+	expected := []TradingStateChange{
+		{From: TradingPreOpen, To: TradingAuction, Reason: "schedule"},
+		{From: TradingAuction, To: TradingContinuous, Reason: "schedule"},
+		{From: TradingContinuous, To: TradingHalted, Reason: "admin"},
+		{From: TradingHalted, To: TradingCancelOnly, Reason: "admin"},
+		{From: TradingCancelOnly, To: TradingClosed, Reason: "schedule"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d state changes, got %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("State change %d: expected %+v, got %+v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestTradingState_ReopeningUncrossesAuctionBook(t *testing.T) {
+	me := NewMatchingEngine(WithInitialTradingState(TradingAuction))
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 105, Size: 10, Side: orderbook.Buy})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 100, Size: 4, Side: orderbook.Sell})
+
+	// The auction ends in a halt: nothing may trade yet
+	if events, _ := me.SetTradingState(TradingHalted); len(events) != 0 {
+		t.Fatalf("Halting must not uncross, got %v", events)
+	}
+	events, err := me.SetTradingState(TradingContinuous)
+	if err != nil || len(events) != 1 || events[0].Size != 4 {
+		t.Fatalf("Expected the crossed book to uncross on reopening, got %v %v", events, err)
+	}
+	bid, ask := me.OrderBook.GetBestBid(), me.OrderBook.GetBestAsk()
+	if bid != nil && ask != nil && bid.Price >= ask.Price {
+		t.Errorf("Book left crossed: bid %d ask %d", bid.Price, ask.Price)
+	}
+}
+
+func TestTradingState_ScheduledUncrossUsesBlockTime(t *testing.T) {
+	me := NewMatchingEngine(
+		WithInitialTradingState(TradingAuction),
+		WithTradingSchedule([]ScheduledTransition{{At: 100, State: TradingContinuous}}),
+	)
+	me.PlaceOrder(&orderbook.Order{ID: 1, Price: 100, Size: 5, Side: orderbook.Sell, Timestamp: 1})
+	me.PlaceOrder(&orderbook.Order{ID: 2, Price: 100, Size: 5, Side: orderbook.Buy, Timestamp: 2})
+
+	res, err := me.AdvanceBlock(1, 100)
+	if err != nil || len(res.Events) != 1 || res.Events[0].Timestamp != 100 {
+		t.Fatalf("Expected one auction fill stamped with the block time, got %v %v", res.Events, err)
+	}
+}
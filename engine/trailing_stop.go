@@ -0,0 +1,48 @@
+package engine
+
+import "orderbook-matching-engine/orderbook"
+
+// trailingStop is a parked trailing stop and the best trade price seen since it was placed:
+// the highest for a sell stop, the lowest for a buy stop
+type trailingStop struct {
+	order *orderbook.Order
+	ref   int64
+}
+
+// trail moves a trailing stop's reference to price if the market moved in its favour and
+// re-queues it at its new trigger. A stop without reference is not triggerable.
+func (b *stopBook) trail(ts *trailingStop, price int64) {
+	if price <= 0 {
+		return
+	}
+	o := ts.order
+	if ts.ref > 0 && ((o.Side == orderbook.Sell && price <= ts.ref) || (o.Side == orderbook.Buy && price >= ts.ref)) {
+		return
+	}
+	offset := o.TrailAmount
+	if o.TrailBps > 0 {
+		offset, _ = mulDiv(price, o.TrailBps, BpsScale)
+	}
+	stopPrice := price + offset
+	if o.Side == orderbook.Sell {
+		stopPrice = price - offset
+	}
+	if ts.ref > 0 {
+		if o.Side == orderbook.Buy {
+			b.buys = deleteOrder(b.buys, o.ID)
+		} else {
+			b.sells = deleteOrder(b.sells, o.ID)
+		}
+	}
+	ts.ref = price
+	o.StopPrice = max(stopPrice, 1)
+	b.insert(o)
+}
+
+// observeTrade records a trade price and lets every trailing stop follow it
+func (me *MatchingEngine) observeTrade(price int64) {
+	me.lastTradePrice = price
+	for _, ts := range me.stops.trailing {
+		me.stops.trail(ts, price)
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"orderbook-matching-engine/orderbook"
+	"reflect"
+	"testing"
+)
+
+// trade prints one unit at price between two throwaway orders
+func trade(me *MatchingEngine, id uint64, price int64) []orderbook.MatchEvent {
+	me.PlaceOrder(&orderbook.Order{ID: id, Price: price, Size: 1, Side: orderbook.Sell, Timestamp: int64(id)})
+	events, _ := me.PlaceOrder(&orderbook.Order{ID: id + 1, Price: price, Size: 1, Side: orderbook.Buy, Timestamp: int64(id)})
+	return events
+}
+
+func TestTrailingStop_FollowsHighAndFires(t *testing.T) {
+	run := func() []orderbook.MatchEvent {
+		me := NewMatchingEngine()
+		trade(me, 100, 100)
+		me.PlaceOrder(&orderbook.Order{ID: 1, Type: orderbook.TrailingStop, TrailAmount: 5, Size: 3, Side: orderbook.Sell, Timestamp: 1})
+		if o, _ := me.GetStopOrder(1); o.StopPrice != 95 {
+			t.Fatalf("Stop should anchor at 95, got %d", o.StopPrice)
+		}
+		me.PlaceOrder(&orderbook.Order{ID: 2, Price: 90, Size: 10, Side: orderbook.Buy, Timestamp: 2})
+
+		trade(me, 200, 110)
+		trade(me, 300, 107)
+		if o, _ := me.GetStopOrder(1); o.StopPrice != 105 {
+			t.Fatalf("Stop should trail the high to 105, got %d", o.StopPrice)
+		}
+
+		events := trade(me, 400, 105)
+		if len(events) != 2 || events[1].TakerOrderID != 1 || events[1].Price != 90 || events[1].Size != 3 {
+			t.Fatalf("Expected trailing stop to sell 3 at 90, got %v", events)
+		}
+		return events
+	}
+	if a, b := run(), run(); !reflect.DeepEqual(a, b) {
+		t.Errorf("Replays diverged: %v vs %v", a, b)
+	}
+}
+
+func TestTrailingStop_BuyPercentageLimit(t *testing.T) {
+	me := NewMatchingEngine()
+	me.PlaceOrder(&orderbook.Order{ID: 1, Type: orderbook.TrailingStopLimit, TrailBps: 1000, Size: 2, Side: orderbook.Buy, Timestamp: 1})
+	if o, _ := me.GetStopOrder(1); o.StopPrice != 0 {
+		t.Fatalf("Stop without a trade should have no trigger")
+	}
+
+	trade(me, 100, 200) // Anchor: stop at 220
+	trade(me, 200, 150) // Low moves: stop at 165
+	if o, _ := me.GetStopOrder(1); o.StopPrice != 165 {
+		t.Fatalf("Stop should trail the low to 165, got %d", o.StopPrice)
+	}
+
+	trade(me, 300, 170)
+	o, ok := me.OrderBook.GetOrder(1)
+	if !ok || o.Type != orderbook.Limit || o.Price != 165 {
+		t.Fatalf("Expected a resting buy limit at 165")
+	}
+
+	if _, err := me.PlaceOrder(&orderbook.Order{ID: 9, Type: orderbook.TrailingStop, TrailAmount: 1, TrailBps: 1, Size: 1, Side: orderbook.Buy}); err != ErrInvalidTrail {
+		t.Errorf("Expected ErrInvalidTrail, got %v", err)
+	}
+}
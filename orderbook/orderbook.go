@@ -7,6 +7,7 @@ import (
 type OrderQueue struct {
 	Head *Order
 	Tail *Order
+	Top  *Order // Order that opened this level by improving the best price (top-order priority)
 }
 
 type OrderBook struct {
@@ -53,6 +54,17 @@ func (ob *OrderBook) addOrderToSkipMap(order *Order) {
 	}
 }
 
+// MarkTopOrder grants top-order priority to an order resting at its price level
+func (ob *OrderBook) MarkTopOrder(order *Order) {
+	sm, key := ob.Asks, order.Price
+	if order.Side == Buy {
+		sm, key = ob.Bids, -order.Price
+	}
+	if val, ok := sm.Load(key); ok {
+		val.(*OrderQueue).Top = order
+	}
+}
+
 // RemoveOrder removes an order by ID
 func (ob *OrderBook) RemoveOrder(orderID uint64) (*Order, bool) {
 	val, exists := ob.OrderMap.LoadAndDelete(orderID)
@@ -83,6 +95,9 @@ func (ob *OrderBook) removeOrderFromSkipMap(order *Order) {
 	}
 	q := val.(*OrderQueue)
 	head := q.Head
+	if q.Top == order {
+		q.Top = nil
+	}
 
 	if head.ID == order.ID {
 		// Removing head
@@ -132,6 +147,50 @@ func (ob *OrderBook) BatchAddMakerOrders(orders []*Order) {
 	}
 }
 
+// LevelSize returns the total resting size at a price level
+func (ob *OrderBook) LevelSize(side Side, price int64) int64 {
+	sm, key := ob.Asks, price
+	if side == Buy {
+		sm, key = ob.Bids, -price
+	}
+	val, ok := sm.Load(key)
+	if !ok {
+		return 0
+	}
+	total := int64(0)
+	for ord := val.(*OrderQueue).Head; ord != nil; ord = ord.Next {
+		total += ord.Size
+	}
+	return total
+}
+
+// RangeOrders calls f for each resting order on a side with price in [minPrice, maxPrice],
+// best price first and in time priority within a level. Iteration stops when f returns false.
+func (ob *OrderBook) RangeOrders(side Side, minPrice, maxPrice int64, f func(order *Order) bool) {
+	sm := ob.Asks
+	if side == Buy {
+		sm = ob.Bids
+	}
+	sm.Range(func(key int64, value interface{}) bool {
+		price := key
+		if side == Buy {
+			price = -key
+		}
+		if price < minPrice || price > maxPrice {
+			// Asks ascend and bids descend, so only the far end of the range ends iteration
+			return (side == Sell && price < minPrice) || (side == Buy && price > maxPrice)
+		}
+		for ord := value.(*OrderQueue).Head; ord != nil; {
+			next := ord.Next
+			if !f(ord) {
+				return false
+			}
+			ord = next
+		}
+		return true
+	})
+}
+
 // GetBestBid returns the highest buy order
 func (ob *OrderBook) GetBestBid() *Order {
 	var best *Order
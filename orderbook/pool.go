@@ -27,11 +27,24 @@ func (o *Order) Reset() {
 	o.ID = 0
 	o.UserID = ""
 	o.OrderHash = ""
+	o.SessionID = ""
 	o.Type = Limit // Default
 	o.Price = 0
+	o.StopPrice = 0
+	o.TrailAmount = 0
+	o.TrailBps = 0
+	o.PegType = PegNone
+	o.PegOffset = 0
+	o.PegLimit = 0
+	o.MinQty = 0
+	o.AllOrNone = false
+	o.ReduceOnly = false
 	o.Size = 0
+	o.QuoteSize = 0
 	o.Side = Buy // Default
 	o.Timestamp = 0
+	o.ExpireAt = 0
+	o.ExpireBlock = 0
 	o.Next = nil
 }
 
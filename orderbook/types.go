@@ -47,13 +47,40 @@ type OrderType int
 const (
 	Limit OrderType = iota
 	Market
+	Stop      // Market order once the last trade price reaches StopPrice
+	StopLimit // Limit order once the last trade price reaches StopPrice
+	// TrailingStop is a Stop whose StopPrice trails the best trade price seen since placement
+	// by TrailAmount or TrailBps
+	TrailingStop
+	// TrailingStopLimit is a TrailingStop that fires as a limit order at its StopPrice
+	TrailingStopLimit
 )
 
 func (t OrderType) String() string {
-	if t == Market {
+	switch t {
+	case Market:
 		return "Market"
+	case Stop:
+		return "Stop"
+	case StopLimit:
+		return "StopLimit"
+	case TrailingStop:
+		return "TrailingStop"
+	case TrailingStopLimit:
+		return "TrailingStopLimit"
+	default:
+		return "Limit"
 	}
-	return "Limit"
+}
+
+// IsStop reports whether the order waits for a trigger before entering the book
+func (t OrderType) IsStop() bool {
+	return t == Stop || t == StopLimit || t.IsTrailing()
+}
+
+// IsTrailing reports whether the order's trigger follows the market
+func (t OrderType) IsTrailing() bool {
+	return t == TrailingStop || t == TrailingStopLimit
 }
 
 func (t OrderType) MarshalJSON() ([]byte, error) {
@@ -70,32 +97,108 @@ func (t *OrderType) UnmarshalJSON(data []byte) error {
 		*t = Limit
 	case "market":
 		*t = Market
+	case "stop":
+		*t = Stop
+	case "stoplimit", "stop_limit":
+		*t = StopLimit
+	case "trailingstop", "trailing_stop":
+		*t = TrailingStop
+	case "trailingstoplimit", "trailing_stop_limit":
+		*t = TrailingStopLimit
 	default:
 		return fmt.Errorf("invalid order type: %s", str)
 	}
 	return nil
 }
 
+// PegReference is the market price a pegged order follows
+type PegReference int
+
+const (
+	PegNone    PegReference = iota
+	PegBestBid              // Best bid
+	PegBestAsk              // Best ask
+	PegMid                  // Midpoint of best bid and best ask
+)
+
+func (p PegReference) String() string {
+	switch p {
+	case PegBestBid:
+		return "BestBid"
+	case PegBestAsk:
+		return "BestAsk"
+	case PegMid:
+		return "Mid"
+	default:
+		return "None"
+	}
+}
+
+func (p PegReference) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *PegReference) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	switch strings.ToLower(str) {
+	case "", "none":
+		*p = PegNone
+	case "bestbid", "best_bid":
+		*p = PegBestBid
+	case "bestask", "best_ask":
+		*p = PegBestAsk
+	case "mid", "midpoint":
+		*p = PegMid
+	default:
+		return fmt.Errorf("invalid peg reference: %s", str)
+	}
+	return nil
+}
+
 // Order represents an order in the system
 type Order struct {
-	ID        uint64    `json:"id"`
-	UserID    string    `json:"user_id"`
-	OrderHash string    `json:"order_hash"`
-	Type      OrderType `json:"type"`
-	Price     int64     `json:"price"` // Fixed-point representation (e.g., * 1e8)
-	Size      int64     `json:"size"`  // Fixed-point representation
-	Side      Side      `json:"side"`
-	Timestamp int64     `json:"timestamp"` // Unix nanoseconds
-	Next      *Order    `json:"-"`         // For SkipList/Linked List linking
+	ID          uint64       `json:"id"`
+	UserID      string       `json:"user_id"`
+	OrderHash   string       `json:"order_hash"`
+	SessionID   string       `json:"session_id,omitempty"` // Gateway session for cancel-on-disconnect
+	Type        OrderType    `json:"type"`
+	Price       int64        `json:"price"`                  // Fixed-point representation (e.g., * 1e8)
+	StopPrice   int64        `json:"stop_price,omitempty"`   // Trigger price of Stop/StopLimit orders
+	TrailAmount int64        `json:"trail_amount,omitempty"` // Trailing stop distance in price units
+	TrailBps    int64        `json:"trail_bps,omitempty"`    // Trailing stop distance in basis points of the reference price
+	PegType     PegReference `json:"peg_type,omitempty"`     // Limit order priced off the book, re-priced as the book moves
+	PegOffset   int64        `json:"peg_offset,omitempty"`   // Added to the peg reference price
+	PegLimit    int64        `json:"peg_limit,omitempty"`    // Worst price a pegged order may follow to, 0 = none
+	MinQty      int64        `json:"min_qty,omitempty"`      // Minimum execution: aggregate entry fill for lit takers, each fill in the dark book
+	AllOrNone   bool         `json:"all_or_none,omitempty"`  // Resting order only trades in full
+	ReduceOnly  bool         `json:"reduce_only,omitempty"`  // May only reduce the owner's position, shrunk or cancelled otherwise
+	Size        int64        `json:"size"`                   // Fixed-point representation
+	QuoteSize   int64        `json:"quote_size,omitempty"`   // Market buy sized by quote notional to spend, Size is derived
+	Side        Side         `json:"side"`
+	Timestamp   int64        `json:"timestamp"`              // Unix nanoseconds
+	ExpireAt    int64        `json:"expire_at,omitempty"`    // Good-till-date/time expiry in Unix nanoseconds, 0 = good-till-cancel
+	ExpireBlock uint64       `json:"expire_block,omitempty"` // Good-till-block: expires once this block height is reached, 0 = none
+	Next        *Order       `json:"-"`                      // For SkipList/Linked List linking
 }
 
 // MatchEvent represents a trade execution
 type MatchEvent struct {
+	TradeID      uint64 `json:"trade_id"` // Sequential per engine, starting at 1
 	MakerOrderID uint64 `json:"maker_order_id"`
 	TakerOrderID uint64 `json:"taker_order_id"`
+	MakerUserID  string `json:"maker_user_id,omitempty"`
+	TakerUserID  string `json:"taker_user_id,omitempty"`
 	Price        int64  `json:"price"`
 	Size         int64  `json:"size"`
 	Timestamp    int64  `json:"timestamp"`
+	// Fees are fixed-point amounts of the named asset, negative for rebates
+	MakerFee      int64  `json:"maker_fee,omitempty"`
+	MakerFeeAsset string `json:"maker_fee_asset,omitempty"`
+	TakerFee      int64  `json:"taker_fee,omitempty"`
+	TakerFeeAsset string `json:"taker_fee_asset,omitempty"`
 }
 
 // DepthSnapshot represents the current state of the order book
@@ -108,3 +211,50 @@ type PriceLevel struct {
 	Price int64 `json:"price"`
 	Size  int64 `json:"size"`
 }
+
+// LevelUpdate represents the new aggregate size of a price level (Size 0 means the level was removed)
+type LevelUpdate struct {
+	Side  Side  `json:"side"`
+	Price int64 `json:"price"`
+	Size  int64 `json:"size"`
+}
+
+// ExecType represents the kind of execution report
+type ExecType int
+
+const (
+	ExecExpired   ExecType = iota
+	ExecTriggered          // Stop order triggered and submitted to the book
+	ExecCancelled          // Cancelled by the engine, e.g. the other leg of an OCO pair
+	ExecRejected           // Engine-submitted order failed admission (funds, risk)
+)
+
+func (e ExecType) String() string {
+	switch e {
+	case ExecExpired:
+		return "Expired"
+	case ExecTriggered:
+		return "Triggered"
+	case ExecCancelled:
+		return "Cancelled"
+	case ExecRejected:
+		return "Rejected"
+	default:
+		return "Unknown"
+	}
+}
+
+func (e ExecType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+// ExecutionReport notifies the owner of an order about a change not caused by its own request
+type ExecutionReport struct {
+	OrderID    uint64   `json:"order_id"`
+	UserID     string   `json:"user_id"`
+	Type       ExecType `json:"type"`
+	Side       Side     `json:"side"`
+	Price      int64    `json:"price"`
+	LeavesSize int64    `json:"leaves_size"` // Size still open when the report was generated
+	Timestamp  int64    `json:"timestamp"`
+}